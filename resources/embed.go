@@ -0,0 +1,18 @@
+//go:build !noembed
+
+// Package resources holds the game files. They are embedded into the
+// binary, so it runs from any directory. Build with the noembed tag to leave
+// them out and ship a packed archive next to the binary instead.
+package resources
+
+import (
+	"embed"
+	"io/fs"
+)
+
+//go:embed manifest.json bosses fonts levels particles shaders sounds textures
+var embedded embed.FS
+
+// FS has the files at their paths below this directory, "shaders/sprite.vert"
+// for example.
+var FS fs.FS = embedded
@@ -0,0 +1,12 @@
+//go:build noembed
+
+package resources
+
+import (
+	"io/fs"
+	"os"
+)
+
+// FS reads the loose files from the resources directory below the working
+// directory, as the game did before the files were embedded.
+var FS fs.FS = os.DirFS("resources")
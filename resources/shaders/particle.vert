@@ -1,16 +1,18 @@
 #version 330 core
 layout (location = 0) in vec4 vertex;
+// Per instance: the centre of the particle, its color and how big it is.
+layout (location = 1) in vec2 offset;
+layout (location = 2) in vec4 color;
+layout (location = 3) in float size;
 
 out vec2 TexCoords;
 out vec4 ParticleColor;
 
 uniform mat4 projection;
-uniform vec2 offset;
-uniform vec4 color;
+uniform vec4 uvRect;
 
 void main() {
-    float scale = 10.0f;
-    TexCoords = vertex.zw;
+    TexCoords = mix(uvRect.xy, uvRect.zw, vertex.zw);
     ParticleColor = color;
-    gl_Position = projection * vec4((vertex.xy * scale) + offset, 0.0, 1.0);
+    gl_Position = projection * vec4((vertex.xy - 0.5) * size + offset, 0.0, 1.0);
 }
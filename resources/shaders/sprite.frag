@@ -1,10 +1,10 @@
 #version 330 core
 in vec2 TexCoords;
+in vec3 SpriteColor;
 out vec4 color;
 
 uniform sampler2D image;
-uniform vec3 spriteColor;
 
 void main() {
-    color = vec4(spriteColor, 1.0) * texture(image, TexCoords);
+    color = vec4(SpriteColor, 1.0) * texture(image, TexCoords);
 }
@@ -1,12 +1,16 @@
 #version 330 core
+// Sprites are transformed on the CPU, so the position is already in world
+// space and the texture coordinates already point into the atlas page.
 layout (location = 0) in vec4 vertex;
+layout (location = 1) in vec3 spriteColor;
 
 out vec2 TexCoords;
+out vec3 SpriteColor;
 
-uniform mat4 model;
 uniform mat4 projection;
 
 void main() {
     TexCoords = vertex.zw;
-    gl_Position = projection * model * vec4(vertex.xy, 0.0, 1.0);
+    SpriteColor = spriteColor;
+    gl_Position = projection * vec4(vertex.xy, 0.0, 1.0);
 }
@@ -2,9 +2,49 @@
 in vec2 TexCoords;
 out vec4 color;
 
+// The alpha of the glyph texture is a distance field, 0.5 on the outline.
 uniform sampler2D text;
 uniform vec3 textColor;
 
+// Widths are distances in the field, the shadow offset is in texture
+// coordinates.
+uniform float outlineWidth;
+uniform vec3 outlineColor;
+uniform vec2 shadowOffset;
+uniform vec4 shadowColor;
+uniform float glowWidth;
+uniform vec4 glowColor;
+
+vec4 over(vec4 top, vec4 bottom) {
+    float alpha = top.a + bottom.a * (1.0 - top.a);
+    if (alpha == 0.0) {
+        return vec4(0.0);
+    }
+
+    return vec4((top.rgb * top.a + bottom.rgb * bottom.a * (1.0 - top.a)) / alpha, alpha);
+}
+
 void main() {
-    color = vec4(textColor, 1.0) * texture(text, TexCoords);
+    float distance = texture(text, TexCoords).a;
+    float smoothing = max(fwidth(distance), 0.001);
+    float edge = 0.5 - outlineWidth;
+
+    float fill = smoothstep(0.5 - smoothing, 0.5 + smoothing, distance);
+    float shape = smoothstep(edge - smoothing, edge + smoothing, distance);
+    vec4 glyph = vec4(mix(outlineColor, textColor, fill), shape);
+
+    vec4 background = vec4(0.0);
+
+    if (glowWidth > 0.0) {
+        float glow = smoothstep(edge - glowWidth, edge, distance);
+        background = vec4(glowColor.rgb, glowColor.a * glow);
+    }
+
+    if (shadowColor.a > 0.0) {
+        float shadowDistance = texture(text, TexCoords - shadowOffset).a;
+        float shadow = smoothstep(edge - smoothing, edge + smoothing, shadowDistance);
+        background = over(vec4(shadowColor.rgb, shadowColor.a * shadow), background);
+    }
+
+    color = over(glyph, background);
 }
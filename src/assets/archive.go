@@ -0,0 +1,262 @@
+package assets
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"time"
+)
+
+// An archive is the packed files followed by an index and a trailer:
+//
+//	header  magic, version
+//	data    every file, stored or deflated
+//	index   entry count, then per entry: name length, name, method, offset,
+//	        packed size, size, CRC-32 of the unpacked contents
+//	trailer index offset, CRC-32 of the index, magic
+//
+// All integers are little endian. The index at the end lets the packer
+// stream the files without knowing their packed sizes up front.
+const (
+	magic   = "BKPK"
+	version = 1
+
+	headerSize  = 8
+	trailerSize = 16
+
+	methodStore   = 0
+	methodDeflate = 1
+
+	// maxFileSize bounds what a deflated entry may claim to unpack to, so a
+	// corrupt index cannot make ReadFile allocate without limit. Larger
+	// files are stored.
+	maxFileSize = 256 << 20
+)
+
+// archiveEntry is the fixed size part of an index entry, as it is encoded.
+type archiveEntry struct {
+	Method     uint8
+	Offset     uint64
+	PackedSize uint64
+	Size       uint64
+	Checksum   uint32
+}
+
+// Archive is a read only fs.FS over a packed archive file. Every file is
+// checked against its checksum when it is opened.
+type Archive struct {
+	file    *os.File
+	entries map[string]archiveEntry
+	modTime time.Time
+}
+
+func OpenArchive(fileName string) (*Archive, error) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open archive: %w", err)
+	}
+
+	a := &Archive{file: file, entries: make(map[string]archiveEntry)}
+	if err := a.readIndex(); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("invalid archive %s: %w", fileName, err)
+	}
+
+	return a, nil
+}
+
+func (a *Archive) readIndex() error {
+	info, err := a.file.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat archive: %w", err)
+	}
+
+	a.modTime = info.ModTime()
+
+	if info.Size() < headerSize+trailerSize {
+		return errors.New("archive is too short")
+	}
+
+	header := make([]byte, headerSize)
+	if _, err := a.file.ReadAt(header, 0); err != nil {
+		return fmt.Errorf("failed to read header: %w", err)
+	}
+
+	if string(header[:4]) != magic {
+		return errors.New("not an archive")
+	}
+
+	if v := binary.LittleEndian.Uint32(header[4:]); v != version {
+		return fmt.Errorf("unsupported archive version %d", v)
+	}
+
+	trailer := make([]byte, trailerSize)
+	if _, err := a.file.ReadAt(trailer, info.Size()-trailerSize); err != nil {
+		return fmt.Errorf("failed to read trailer: %w", err)
+	}
+
+	if string(trailer[12:]) != magic {
+		return errors.New("archive is truncated")
+	}
+
+	indexOffset := binary.LittleEndian.Uint64(trailer)
+	indexEnd := uint64(info.Size() - trailerSize)
+	if indexOffset < headerSize || indexOffset > indexEnd {
+		return errors.New("index is out of bounds")
+	}
+
+	index := make([]byte, indexEnd-indexOffset)
+	if _, err := a.file.ReadAt(index, int64(indexOffset)); err != nil {
+		return fmt.Errorf("failed to read index: %w", err)
+	}
+
+	if crc32.ChecksumIEEE(index) != binary.LittleEndian.Uint32(trailer[8:]) {
+		return errors.New("index checksum mismatch")
+	}
+
+	r := bytes.NewReader(index)
+
+	var count uint32
+	if err := binary.Read(r, binary.LittleEndian, &count); err != nil {
+		return fmt.Errorf("failed to read entry count: %w", err)
+	}
+
+	for i := uint32(0); i < count; i++ {
+		var nameLength uint16
+		if err := binary.Read(r, binary.LittleEndian, &nameLength); err != nil {
+			return fmt.Errorf("failed to read entry %d: %w", i, err)
+		}
+
+		name := make([]byte, nameLength)
+		if _, err := io.ReadFull(r, name); err != nil {
+			return fmt.Errorf("failed to read entry %d name: %w", i, err)
+		}
+
+		var e archiveEntry
+		if err := binary.Read(r, binary.LittleEndian, &e); err != nil {
+			return fmt.Errorf("failed to read entry %s: %w", name, err)
+		}
+
+		if err := e.validate(indexOffset); err != nil {
+			return fmt.Errorf("entry %s: %w", name, err)
+		}
+
+		a.entries[string(name)] = e
+	}
+
+	return nil
+}
+
+// validate checks an entry read from the index before it is trusted. The
+// comparisons are written so that they cannot overflow.
+func (e *archiveEntry) validate(indexOffset uint64) error {
+	if e.Offset < headerSize || e.Offset > indexOffset || e.PackedSize > indexOffset-e.Offset {
+		return errors.New("out of bounds")
+	}
+
+	switch e.Method {
+	case methodStore:
+		if e.Size != e.PackedSize {
+			return fmt.Errorf("stored size %d differs from packed size %d", e.Size, e.PackedSize)
+		}
+	case methodDeflate:
+		if e.Size > maxFileSize {
+			return fmt.Errorf("size %d is over the limit of %d", e.Size, maxFileSize)
+		}
+	default:
+		return fmt.Errorf("unknown compression method %d", e.Method)
+	}
+
+	return nil
+}
+
+func (a *Archive) Open(name string) (fs.File, error) {
+	data, err := a.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return &memoryFile{Reader: bytes.NewReader(data), info: fileInfo{name: path.Base(name), size: int64(len(data)), modTime: a.modTime}}, nil
+}
+
+// ReadFile unpacks a file and checks it against its checksum.
+func (a *Archive) ReadFile(name string) ([]byte, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+
+	e, ok := a.entries[name]
+	if !ok {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+
+	var r io.Reader = io.NewSectionReader(a.file, int64(e.Offset), int64(e.PackedSize))
+	switch e.Method {
+	case methodStore:
+	case methodDeflate:
+		r = flate.NewReader(r)
+	default:
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fmt.Errorf("unknown compression method %d", e.Method)}
+	}
+
+	data := make([]byte, e.Size)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fmt.Errorf("failed to unpack: %w", err)}
+	}
+
+	if crc32.ChecksumIEEE(data) != e.Checksum {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: errors.New("checksum mismatch")}
+	}
+
+	return data, nil
+}
+
+func (a *Archive) Stat(name string) (fs.FileInfo, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrInvalid}
+	}
+
+	e, ok := a.entries[name]
+	if !ok {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrNotExist}
+	}
+
+	return fileInfo{name: path.Base(name), size: int64(e.Size), modTime: a.modTime}, nil
+}
+
+func (a *Archive) Close() error {
+	return a.file.Close()
+}
+
+type memoryFile struct {
+	*bytes.Reader
+	info fileInfo
+}
+
+func (f *memoryFile) Stat() (fs.FileInfo, error) {
+	return f.info, nil
+}
+
+func (f *memoryFile) Close() error {
+	return nil
+}
+
+type fileInfo struct {
+	name    string
+	size    int64
+	modTime time.Time
+}
+
+func (i fileInfo) Name() string       { return i.name }
+func (i fileInfo) Size() int64        { return i.size }
+func (i fileInfo) Mode() fs.FileMode  { return 0o444 }
+func (i fileInfo) ModTime() time.Time { return i.modTime }
+func (i fileInfo) IsDir() bool        { return false }
+func (i fileInfo) Sys() any           { return nil }
@@ -0,0 +1,196 @@
+package assets
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"sort"
+)
+
+// Manifest lists every file the game loads under a logical name. Paths are
+// relative to the root of the assets file system.
+//
+// Music is optional: a missing track only leaves the game silent, so it is
+// reported apart from the files the game needs.
+type Manifest struct {
+	Shaders   map[string]ShaderAsset  `json:"shaders"`
+	Textures  map[string]TextureAsset `json:"textures"`
+	Fonts     map[string]FontAsset    `json:"fonts"`
+	Sounds    map[string]string       `json:"sounds"`
+	Music     map[string]string       `json:"music"`
+	Bosses    map[string]string       `json:"bosses"`
+	Particles map[string]string       `json:"particles"`
+	Levels    []string                `json:"levels"`
+}
+
+type ShaderAsset struct {
+	Vertex   string `json:"vertex"`
+	Fragment string `json:"fragment"`
+	Geometry string `json:"geometry"`
+}
+
+type TextureAsset struct {
+	Path  string `json:"path"`
+	Alpha bool   `json:"alpha"`
+
+	// Filter is "linear", the default, or "nearest" for pixel art.
+	Filter string `json:"filter"`
+
+	// Atlas packs the texture into a page shared with other sprites.
+	Atlas bool `json:"atlas"`
+}
+
+func (t TextureAsset) Nearest() bool {
+	return t.Filter == "nearest"
+}
+
+type FontAsset struct {
+	Path string `json:"path"`
+	Size int    `json:"size"`
+
+	// Fallback fonts are searched in order for glyphs the font lacks.
+	Fallback []string `json:"fallback"`
+}
+
+type asset struct {
+	kind, name, path string
+}
+
+func (a asset) optional() bool {
+	return a.kind == "music"
+}
+
+func LoadManifest(fsys fs.FS, fileName string) (*Manifest, error) {
+	data, err := fs.ReadFile(fsys, fileName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+
+	var m Manifest
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+
+	if err := decoder.Decode(&m); err != nil {
+		return nil, fmt.Errorf("failed to decode manifest: %w", err)
+	}
+
+	if err := m.check(); err != nil {
+		return nil, fmt.Errorf("invalid manifest %s:\n%w", fileName, err)
+	}
+
+	return &m, nil
+}
+
+func (m *Manifest) check() error {
+	var errs []error
+
+	for name, s := range m.Shaders {
+		if s.Vertex == "" || s.Fragment == "" {
+			errs = append(errs, fmt.Errorf("shader %s: vertex and fragment files are required", name))
+		}
+	}
+
+	for name, t := range m.Textures {
+		if t.Filter != "" && t.Filter != "linear" && t.Filter != "nearest" {
+			errs = append(errs, fmt.Errorf("texture %s: unknown filter %q", name, t.Filter))
+		}
+	}
+
+	for name, f := range m.Fonts {
+		if f.Size <= 0 {
+			errs = append(errs, fmt.Errorf("font %s: size should be positive, got %d", name, f.Size))
+		}
+	}
+
+	for _, a := range m.assets() {
+		if !fs.ValidPath(a.path) {
+			errs = append(errs, fmt.Errorf("%s %s: invalid path %q", a.kind, a.name, a.path))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Validate checks that every required file exists and reports all the
+// missing ones at once.
+func (m *Manifest) Validate(fsys fs.FS) error {
+	return m.missing(fsys, false)
+}
+
+// MissingOptional reports the optional files, the music, that are missing.
+func (m *Manifest) MissingOptional(fsys fs.FS) error {
+	return m.missing(fsys, true)
+}
+
+func (m *Manifest) missing(fsys fs.FS, optional bool) error {
+	var errs []error
+
+	for _, a := range m.assets() {
+		if a.optional() != optional {
+			continue
+		}
+
+		if _, err := fs.Stat(fsys, a.path); err != nil {
+			errs = append(errs, fmt.Errorf("%s %s: %w", a.kind, a.name, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// assets lists every file in a stable order, so problems are always
+// reported the same way.
+func (m *Manifest) assets() []asset {
+	var list []asset
+
+	for name, s := range m.Shaders {
+		for _, path := range []string{s.Vertex, s.Fragment, s.Geometry} {
+			if path != "" {
+				list = append(list, asset{"shader", name, path})
+			}
+		}
+	}
+
+	for name, t := range m.Textures {
+		list = append(list, asset{"texture", name, t.Path})
+	}
+
+	for name, f := range m.Fonts {
+		for _, path := range append([]string{f.Path}, f.Fallback...) {
+			list = append(list, asset{"font", name, path})
+		}
+	}
+
+	for name, path := range m.Sounds {
+		list = append(list, asset{"sound", name, path})
+	}
+
+	for name, path := range m.Music {
+		list = append(list, asset{"music", name, path})
+	}
+
+	for name, path := range m.Bosses {
+		list = append(list, asset{"boss", name, path})
+	}
+
+	for name, path := range m.Particles {
+		list = append(list, asset{"particles", name, path})
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].kind != list[j].kind {
+			return list[i].kind < list[j].kind
+		}
+
+		return list[i].name < list[j].name || list[i].name == list[j].name && list[i].path < list[j].path
+	})
+
+	for i, path := range m.Levels {
+		list = append(list, asset{"level", fmt.Sprint(i + 1), path})
+	}
+
+	return list
+}
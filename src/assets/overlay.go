@@ -0,0 +1,30 @@
+package assets
+
+import (
+	"errors"
+	"io/fs"
+)
+
+type overlay []fs.FS
+
+// Overlay stacks file systems, a file is read from the first one that has
+// it. Loose files put over the packed ones this way replace them, which is
+// how mods change the game.
+func Overlay(layers ...fs.FS) fs.FS {
+	return overlay(layers)
+}
+
+func (o overlay) Open(name string) (fs.File, error) {
+	for _, layer := range o {
+		f, err := layer.Open(name)
+		if err == nil {
+			return f, nil
+		}
+
+		if !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+	}
+
+	return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+}
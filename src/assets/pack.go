@@ -0,0 +1,123 @@
+package assets
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"io/fs"
+)
+
+type countingWriter struct {
+	w io.Writer
+	n uint64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += uint64(n)
+
+	return n, err
+}
+
+// Pack writes every file of fsys into an archive. Files are deflated unless
+// that does not make them smaller, the sounds are compressed already.
+func Pack(w io.Writer, fsys fs.FS) error {
+	out := &countingWriter{w: w}
+
+	header := make([]byte, headerSize)
+	copy(header, magic)
+	binary.LittleEndian.PutUint32(header[4:], version)
+	if _, err := out.Write(header); err != nil {
+		return fmt.Errorf("failed to write header: %w", err)
+	}
+
+	var index bytes.Buffer
+	count := uint32(0)
+
+	err := fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", name, err)
+		}
+
+		e := archiveEntry{
+			Method:   methodStore,
+			Offset:   out.n,
+			Size:     uint64(len(data)),
+			Checksum: crc32.ChecksumIEEE(data),
+		}
+
+		packed, err := deflate(data)
+		if err != nil {
+			return fmt.Errorf("failed to compress %s: %w", name, err)
+		}
+
+		if len(packed) < len(data) && len(data) <= maxFileSize {
+			data = packed
+			e.Method = methodDeflate
+		}
+
+		e.PackedSize = uint64(len(data))
+
+		if _, err := out.Write(data); err != nil {
+			return fmt.Errorf("failed to write %s: %w", name, err)
+		}
+
+		binary.Write(&index, binary.LittleEndian, uint16(len(name)))
+		index.WriteString(name)
+		binary.Write(&index, binary.LittleEndian, e)
+		count++
+
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to pack files: %w", err)
+	}
+
+	indexOffset := out.n
+
+	var counted bytes.Buffer
+	binary.Write(&counted, binary.LittleEndian, count)
+	counted.Write(index.Bytes())
+
+	trailer := make([]byte, trailerSize)
+	binary.LittleEndian.PutUint64(trailer, indexOffset)
+	binary.LittleEndian.PutUint32(trailer[8:], crc32.ChecksumIEEE(counted.Bytes()))
+	copy(trailer[12:], magic)
+
+	if _, err := out.Write(counted.Bytes()); err != nil {
+		return fmt.Errorf("failed to write index: %w", err)
+	}
+
+	if _, err := out.Write(trailer); err != nil {
+		return fmt.Errorf("failed to write trailer: %w", err)
+	}
+
+	return nil
+}
+
+func deflate(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+
+	w, err := flate.NewWriter(&buf, flate.BestCompression)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,72 @@
+package controls
+
+import "fmt"
+
+type Action int
+
+const (
+	MoveLeft Action = iota
+	MoveRight
+	Launch
+	Confirm
+	MenuUp
+	MenuDown
+	Pause
+	CycleMode
+	OpenControls
+	ToggleMouse
+	CaptureCursor
+	OpenSettings
+
+	actionCount
+)
+
+const Players = 2
+
+var actionNames = [actionCount]string{
+	MoveLeft:      "move_left",
+	MoveRight:     "move_right",
+	Launch:        "launch",
+	Confirm:       "confirm",
+	MenuUp:        "menu_up",
+	MenuDown:      "menu_down",
+	Pause:         "pause",
+	CycleMode:     "cycle_mode",
+	OpenControls:  "open_controls",
+	ToggleMouse:   "toggle_mouse",
+	CaptureCursor: "capture_cursor",
+	OpenSettings:  "open_settings",
+}
+
+func (a Action) String() string {
+	if a < 0 || a >= actionCount {
+		return "unknown"
+	}
+
+	return actionNames[a]
+}
+
+// PerPlayer reports whether every player has their own keys for the action.
+// The other actions drive menus and are shared.
+func (a Action) PerPlayer() bool {
+	return a == MoveLeft || a == MoveRight || a == Launch
+}
+
+func Actions() []Action {
+	actions := make([]Action, 0, actionCount)
+	for a := Action(0); a < actionCount; a++ {
+		actions = append(actions, a)
+	}
+
+	return actions
+}
+
+func ParseAction(name string) (Action, error) {
+	for a, n := range actionNames {
+		if n == name {
+			return Action(a), nil
+		}
+	}
+
+	return 0, fmt.Errorf("unknown action %q", name)
+}
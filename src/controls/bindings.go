@@ -0,0 +1,200 @@
+package controls
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/go-gl/glfw/v3.3/glfw"
+)
+
+// Bindings maps every action to the keys that trigger it. Per player
+// actions have a key list for each player, shared actions use player 0.
+type Bindings struct {
+	keys [Players][actionCount][]glfw.Key
+}
+
+func DefaultBindings() *Bindings {
+	b := &Bindings{}
+
+	b.Set(0, MoveLeft, glfw.KeyA)
+	b.Set(0, MoveRight, glfw.KeyD)
+	b.Set(0, Launch, glfw.KeySpace)
+	b.Set(1, MoveLeft, glfw.KeyLeft)
+	b.Set(1, MoveRight, glfw.KeyRight)
+	b.Set(1, Launch, glfw.KeyUp)
+
+	b.Set(0, Confirm, glfw.KeyEnter)
+	b.Set(0, MenuUp, glfw.KeyW, glfw.KeyUp)
+	b.Set(0, MenuDown, glfw.KeyS, glfw.KeyDown)
+	b.Set(0, Pause, glfw.KeyP)
+	b.Set(0, CycleMode, glfw.KeyM)
+	b.Set(0, OpenControls, glfw.KeyC)
+	b.Set(0, ToggleMouse, glfw.KeyF2)
+	b.Set(0, CaptureCursor, glfw.KeyF3)
+	b.Set(0, OpenSettings, glfw.KeyO)
+
+	return b
+}
+
+// Parse sets the keys of a binding from its settings form, for example
+// "confirm" and "ENTER" or "p2.move_left" and "LEFT, J".
+func (b *Bindings) Parse(name, value string) error {
+	player, action, err := parseBindingName(name)
+	if err != nil {
+		return err
+	}
+
+	var keys []glfw.Key
+	for _, field := range strings.Split(value, ",") {
+		if strings.TrimSpace(field) == "" {
+			continue
+		}
+
+		key, err := ParseKey(field)
+		if err != nil {
+			return err
+		}
+
+		keys = append(keys, key)
+	}
+
+	b.Set(player, action, keys...)
+
+	return nil
+}
+
+// Format lists the keys of a binding the way Parse reads them.
+func (b *Bindings) Format(name string) (string, error) {
+	player, action, err := parseBindingName(name)
+	if err != nil {
+		return "", err
+	}
+
+	keys := b.Keys(player, action)
+	names := make([]string, 0, len(keys))
+	for _, key := range keys {
+		names = append(names, KeyName(key))
+	}
+
+	return strings.Join(names, ", "), nil
+}
+
+func parseBindingName(name string) (int, Action, error) {
+	name = strings.TrimSpace(name)
+	player := 0
+
+	if prefix, rest, ok := strings.Cut(name, "."); ok {
+		n, err := strconv.Atoi(strings.TrimPrefix(prefix, "p"))
+		if !strings.HasPrefix(prefix, "p") || err != nil || n < 1 || n > Players {
+			return 0, 0, fmt.Errorf("unknown player %q", prefix)
+		}
+
+		player, name = n-1, rest
+	}
+
+	action, err := ParseAction(name)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	if player > 0 && !action.PerPlayer() {
+		return 0, 0, fmt.Errorf("action %s is shared by all players", action)
+	}
+
+	return player, action, nil
+}
+
+// Names lists every binding name in action order.
+func Names() []string {
+	names := make([]string, 0, actionCount*Players)
+	for _, action := range Actions() {
+		players := 1
+		if action.PerPlayer() {
+			players = Players
+		}
+
+		for player := 0; player < players; player++ {
+			names = append(names, BindingName(player, action))
+		}
+	}
+
+	return names
+}
+
+// BindingName is the name of an action in the settings file.
+func BindingName(player int, action Action) string {
+	if action.PerPlayer() {
+		return fmt.Sprintf("p%d.%s", player+1, action)
+	}
+
+	return action.String()
+}
+
+func (b *Bindings) Keys(player int, action Action) []glfw.Key {
+	if !action.PerPlayer() {
+		player = 0
+	}
+
+	return b.keys[player][action]
+}
+
+func (b *Bindings) Set(player int, action Action, keys ...glfw.Key) {
+	if !action.PerPlayer() {
+		player = 0
+	}
+
+	b.keys[player][action] = keys
+}
+
+// Rebind makes key the only key of the action and takes it away from the
+// actions that are read at the same time, gameplay or menu ones. An action
+// that loses its last key gets the old keys of the rebound one instead, so
+// the two swap. Confirm and Pause always keep a key, the rebind is refused
+// when the swap would leave them without one.
+func (b *Bindings) Rebind(player int, action Action, key glfw.Key) error {
+	if !action.PerPlayer() {
+		player = 0
+	}
+
+	var previous []glfw.Key
+	for _, k := range b.keys[player][action] {
+		if k != key {
+			previous = append(previous, k)
+		}
+	}
+
+	keys := b.keys
+	for p := range keys {
+		for a := range keys[p] {
+			switch {
+			case Action(a).PerPlayer() != action.PerPlayer(), !Action(a).PerPlayer() && p > 0:
+				continue
+			case p == player && Action(a) == action:
+				continue
+			}
+
+			remaining := keys[p][a][:0:0]
+			for _, k := range keys[p][a] {
+				if k != key {
+					remaining = append(remaining, k)
+				}
+			}
+
+			if len(remaining) == 0 && len(keys[p][a]) > 0 {
+				remaining = previous
+			}
+
+			if len(remaining) == 0 && (Action(a) == Confirm || Action(a) == Pause) {
+				return fmt.Errorf("%s needs a key", Action(a))
+			}
+
+			keys[p][a] = remaining
+		}
+	}
+
+	b.keys = keys
+	b.Set(player, action, key)
+
+	return nil
+}
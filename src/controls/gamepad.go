@@ -0,0 +1,132 @@
+package controls
+
+import (
+	"math"
+
+	"github.com/go-gl/glfw/v3.3/glfw"
+)
+
+const (
+	noGamepad = glfw.Joystick(-1)
+
+	// StickDeadzone is the part of the stick travel that is ignored so worn
+	// sticks do not drift the paddle.
+	StickDeadzone = 0.25
+)
+
+var padButtons = map[glfw.GamepadButton][]Action{
+	glfw.ButtonA:         {Launch, Confirm},
+	glfw.ButtonB:         {Pause},
+	glfw.ButtonStart:     {Pause},
+	glfw.ButtonY:         {CycleMode},
+	glfw.ButtonBack:      {OpenControls},
+	glfw.ButtonGuide:     {OpenSettings},
+	glfw.ButtonDpadLeft:  {MoveLeft},
+	glfw.ButtonDpadRight: {MoveRight},
+	glfw.ButtonDpadUp:    {MenuUp},
+	glfw.ButtonDpadDown:  {MenuDown},
+}
+
+type gamepad struct {
+	joystick glfw.Joystick
+	down     [actionCount]bool
+	previous [actionCount]bool
+	axis     float32
+}
+
+// DetectGamepads assigns the gamepads that are already connected, in
+// joystick order, to the players without one.
+func (s *State) DetectGamepads() {
+	for joy := glfw.Joystick1; joy <= glfw.JoystickLast; joy++ {
+		s.OnJoystick(joy, glfw.Connected)
+	}
+}
+
+// OnJoystick hot-plugs gamepads. It matches the glfw joystick callback.
+func (s *State) OnJoystick(joy glfw.Joystick, event glfw.PeripheralEvent) {
+	switch event {
+	case glfw.Connected:
+		if !joy.Present() || !joy.IsGamepad() || s.gamepadPlayer(joy) >= 0 {
+			return
+		}
+
+		for player := range s.pads {
+			if s.pads[player].joystick == noGamepad {
+				s.pads[player] = gamepad{joystick: joy}
+				return
+			}
+		}
+	case glfw.Disconnected:
+		if player := s.gamepadPlayer(joy); player >= 0 {
+			s.pads[player] = gamepad{joystick: noGamepad}
+		}
+	}
+}
+
+// Gamepad returns the name of the gamepad driving a player, if any.
+func (s *State) Gamepad(player int) (string, bool) {
+	if player < 0 || player >= Players || s.pads[player].joystick == noGamepad {
+		return "", false
+	}
+
+	return s.pads[player].joystick.GetGamepadName(), true
+}
+
+// Axis returns the horizontal stick position of a player's gamepad with the
+// deadzone removed and the rest rescaled to [-1, 1].
+func (s *State) Axis(player int) float32 {
+	if player < 0 || player >= Players {
+		return 0
+	}
+
+	return s.pads[player].axis
+}
+
+func (s *State) gamepadPlayer(joy glfw.Joystick) int {
+	for player := range s.pads {
+		if s.pads[player].joystick == joy {
+			return player
+		}
+	}
+
+	return -1
+}
+
+func (s *State) pollGamepads() {
+	for player := range s.pads {
+		pad := &s.pads[player]
+		pad.previous = pad.down
+		pad.down = [actionCount]bool{}
+		pad.axis = 0
+
+		if pad.joystick == noGamepad {
+			continue
+		}
+
+		state := pad.joystick.GetGamepadState()
+		if state == nil {
+			continue
+		}
+
+		for button, actions := range padButtons {
+			if state.Buttons[button] == glfw.Press {
+				for _, action := range actions {
+					pad.down[action] = true
+				}
+			}
+		}
+
+		pad.axis = applyDeadzone(state.Axes[glfw.AxisLeftX])
+	}
+}
+
+func applyDeadzone(value float32) float32 {
+	magnitude := math.Abs(float64(value))
+	if magnitude < StickDeadzone {
+		return 0
+	}
+
+	scaled := (math.Min(magnitude, 1) - StickDeadzone) / (1 - StickDeadzone)
+
+	return float32(math.Copysign(scaled, float64(value)))
+}
@@ -0,0 +1,95 @@
+package controls
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/go-gl/glfw/v3.3/glfw"
+)
+
+var keyNames = map[glfw.Key]string{
+	glfw.KeySpace:        "SPACE",
+	glfw.KeyApostrophe:   "APOSTROPHE",
+	glfw.KeyComma:        "COMMA",
+	glfw.KeyMinus:        "MINUS",
+	glfw.KeyPeriod:       "PERIOD",
+	glfw.KeySlash:        "SLASH",
+	glfw.KeySemicolon:    "SEMICOLON",
+	glfw.KeyEqual:        "EQUAL",
+	glfw.KeyLeftBracket:  "LEFT_BRACKET",
+	glfw.KeyBackslash:    "BACKSLASH",
+	glfw.KeyRightBracket: "RIGHT_BRACKET",
+	glfw.KeyGraveAccent:  "GRAVE",
+	glfw.KeyEscape:       "ESCAPE",
+	glfw.KeyEnter:        "ENTER",
+	glfw.KeyTab:          "TAB",
+	glfw.KeyBackspace:    "BACKSPACE",
+	glfw.KeyInsert:       "INSERT",
+	glfw.KeyDelete:       "DELETE",
+	glfw.KeyRight:        "RIGHT",
+	glfw.KeyLeft:         "LEFT",
+	glfw.KeyDown:         "DOWN",
+	glfw.KeyUp:           "UP",
+	glfw.KeyPageUp:       "PAGE_UP",
+	glfw.KeyPageDown:     "PAGE_DOWN",
+	glfw.KeyHome:         "HOME",
+	glfw.KeyEnd:          "END",
+	glfw.KeyKPEnter:      "KP_ENTER",
+	glfw.KeyLeftShift:    "LEFT_SHIFT",
+	glfw.KeyLeftControl:  "LEFT_CONTROL",
+	glfw.KeyLeftAlt:      "LEFT_ALT",
+	glfw.KeyRightShift:   "RIGHT_SHIFT",
+	glfw.KeyRightControl: "RIGHT_CONTROL",
+	glfw.KeyRightAlt:     "RIGHT_ALT",
+}
+
+func init() {
+	for i := 0; i < 26; i++ {
+		keyNames[glfw.KeyA+glfw.Key(i)] = string(rune('A' + i))
+	}
+
+	for i := 0; i < 10; i++ {
+		keyNames[glfw.Key0+glfw.Key(i)] = string(rune('0' + i))
+		keyNames[glfw.KeyKP0+glfw.Key(i)] = fmt.Sprintf("KP_%d", i)
+	}
+
+	for i := 0; i < 12; i++ {
+		keyNames[glfw.KeyF1+glfw.Key(i)] = fmt.Sprintf("F%d", i+1)
+	}
+}
+
+// KeyName returns the layout independent name used in binding files.
+func KeyName(key glfw.Key) string {
+	if name, ok := keyNames[key]; ok {
+		return name
+	}
+
+	return fmt.Sprintf("KEY_%d", int(key))
+}
+
+// DisplayName returns the label printed on the key in the current keyboard
+// layout, so players with non-QWERTY layouts see their own key caps.
+func DisplayName(key glfw.Key) string {
+	if name := glfw.GetKeyName(key, 0); name != "" {
+		return strings.ToUpper(name)
+	}
+
+	return KeyName(key)
+}
+
+func ParseKey(name string) (glfw.Key, error) {
+	name = strings.ToUpper(strings.TrimSpace(name))
+
+	for key, n := range keyNames {
+		if n == name {
+			return key, nil
+		}
+	}
+
+	var code int
+	if _, err := fmt.Sscanf(name, "KEY_%d", &code); err == nil && code >= 0 && code <= int(glfw.KeyLast) {
+		return glfw.Key(code), nil
+	}
+
+	return glfw.KeyUnknown, fmt.Errorf("unknown key %q", name)
+}
@@ -0,0 +1,137 @@
+package controls
+
+import "github.com/go-gl/glfw/v3.3/glfw"
+
+type MouseSettings struct {
+	Enabled bool
+	// Smoothing is how many seconds the paddle takes to close most of the
+	// gap to the cursor, zero follows as fast as MaxSpeed allows.
+	Smoothing float64
+	MaxSpeed  float32
+}
+
+type mouse struct {
+	x, y       float64
+	rawX, rawY float64
+	viewport   [4]float64
+	seen       bool
+	captured   bool
+
+	down     bool
+	pressed  bool
+	released bool
+}
+
+// OnCursor records a cursor position in window coordinates. A captured
+// cursor reports unbounded virtual positions, so its movement is applied
+// to the last position and kept inside the viewport instead.
+func (s *State) OnCursor(x, y float64) {
+	m := &s.mouse
+
+	if m.captured && m.seen {
+		v := m.viewport
+		m.x = clampRange(m.x+x-m.rawX, v[0], v[0]+v[2])
+		m.y = clampRange(m.y+y-m.rawY, v[1], v[1]+v[3])
+	} else {
+		m.x, m.y = x, y
+	}
+
+	m.rawX, m.rawY = x, y
+	m.seen = true
+}
+
+func (s *State) OnMouseButton(button glfw.MouseButton, action glfw.Action) {
+	if button != glfw.MouseButtonLeft {
+		return
+	}
+
+	switch action {
+	case glfw.Press:
+		s.mouse.down = true
+		s.mouse.pressed = true
+	case glfw.Release:
+		s.mouse.down = false
+		s.mouse.released = true
+	}
+}
+
+// SetViewport records where the game is drawn inside the window, in the
+// screen coordinates cursor positions are reported in, with the origin at
+// the top left.
+func (s *State) SetViewport(x, y, width, height float64) {
+	s.mouse.viewport = [4]float64{x, y, width, height}
+}
+
+// Pointer returns the cursor position as a fraction of the viewport, clamped
+// to it, when mouse control is enabled.
+func (s *State) Pointer() (float32, float32, bool) {
+	m := &s.mouse
+	v := m.viewport
+	if !s.Mouse.Enabled || !m.seen || v[2] <= 0 || v[3] <= 0 {
+		return 0, 0, false
+	}
+
+	x := clampRange((m.x-v[0])/v[2], 0, 1)
+	y := clampRange((m.y-v[1])/v[3], 0, 1)
+
+	return float32(x), float32(y), true
+}
+
+// Cursor returns the cursor position as a fraction of the viewport for
+// menus, whether or not the mouse controls the paddle. It is false while the
+// cursor is captured or outside the viewport.
+func (s *State) Cursor() (float32, float32, bool) {
+	m := &s.mouse
+	v := m.viewport
+	if !m.seen || m.captured || v[2] <= 0 || v[3] <= 0 {
+		return 0, 0, false
+	}
+
+	x, y := (m.x-v[0])/v[2], (m.y-v[1])/v[3]
+	if x < 0 || y < 0 || x >= 1 || y >= 1 {
+		return 0, 0, false
+	}
+
+	return float32(x), float32(y), true
+}
+
+// Clicked reports whether the left button was pressed this frame, for
+// menus.
+func (s *State) Clicked() bool {
+	return s.clicked
+}
+
+// SetCursorCaptured hides the cursor and keeps it inside the window. The
+// window applies it, see CursorCaptured.
+func (s *State) SetCursorCaptured(captured bool) {
+	s.mouse.captured = captured
+}
+
+func (s *State) CursorCaptured() bool {
+	return s.mouse.captured && s.Mouse.Enabled
+}
+
+func (s *State) updateMouse() (held, pressed, released bool) {
+	m := &s.mouse
+	if !s.Mouse.Enabled {
+		m.pressed, m.released = false, false
+		return false, false, false
+	}
+
+	held, pressed, released = m.down || m.pressed, m.pressed, m.released && !m.down && !m.pressed
+	m.released = m.released && m.pressed && !m.down
+	m.pressed = false
+
+	return held, pressed, released
+}
+
+func clampRange(v, lo, hi float64) float64 {
+	if v < lo {
+		return lo
+	}
+	if v > hi {
+		return hi
+	}
+
+	return v
+}
@@ -0,0 +1,155 @@
+package controls
+
+import "github.com/go-gl/glfw/v3.3/glfw"
+
+// State turns raw key, mouse and gamepad input into per frame action
+// states. Key events are recorded as they arrive and folded into action
+// states by Update, so a tap shorter than a frame still counts as pressed.
+type State struct {
+	bindings *Bindings
+
+	down     [glfw.KeyLast + 1]bool
+	pressed  [glfw.KeyLast + 1]bool
+	released [glfw.KeyLast + 1]bool
+
+	framePressed [glfw.KeyLast + 1]bool
+	anyPressed   bool
+	clicked      bool
+
+	held         [Players][actionCount]bool
+	justPressed  [Players][actionCount]bool
+	justReleased [Players][actionCount]bool
+
+	pads  [Players]gamepad
+	mouse mouse
+
+	Mouse MouseSettings
+}
+
+func NewState(bindings *Bindings) *State {
+	s := &State{bindings: bindings}
+	for player := range s.pads {
+		s.pads[player].joystick = noGamepad
+	}
+
+	return s
+}
+
+func (s *State) Bindings() *Bindings {
+	return s.bindings
+}
+
+func (s *State) SetBindings(bindings *Bindings) {
+	s.bindings = bindings
+}
+
+func (s *State) OnKey(key glfw.Key, action glfw.Action) {
+	if key < 0 || key > glfw.KeyLast {
+		return
+	}
+
+	switch action {
+	case glfw.Press:
+		s.down[key] = true
+		s.pressed[key] = true
+	case glfw.Release:
+		s.down[key] = false
+		s.released[key] = true
+	}
+}
+
+// Update computes this frame's action states from the key events since the
+// previous call. Call it once per frame before reading any state.
+func (s *State) Update() {
+	s.framePressed = s.pressed
+	s.anyPressed = false
+
+	for key := range s.pressed {
+		if s.pressed[key] {
+			s.anyPressed = true
+		}
+	}
+
+	s.pollGamepads()
+	s.clicked = s.mouse.pressed
+	mouseHeld, mousePressed, mouseReleased := s.updateMouse()
+	s.anyPressed = s.anyPressed || mousePressed
+
+	for player := 0; player < Players; player++ {
+		for action := Action(0); action < actionCount; action++ {
+			var held, pressed, released bool
+
+			for _, key := range s.bindings.Keys(player, action) {
+				held = held || s.down[key] || s.pressed[key]
+				pressed = pressed || s.pressed[key]
+				released = released || s.released[key]
+			}
+
+			for i := range s.pads {
+				if action.PerPlayer() && i != player {
+					continue
+				}
+
+				pad := &s.pads[i]
+				held = held || pad.down[action]
+				pressed = pressed || pad.down[action] && !pad.previous[action]
+				released = released || !pad.down[action] && pad.previous[action]
+				s.anyPressed = s.anyPressed || pad.down[action] && !pad.previous[action]
+			}
+
+			if player == 0 && action == Launch {
+				held = held || mouseHeld
+				pressed = pressed || mousePressed
+				released = released || mouseReleased
+			}
+
+			s.held[player][action] = held
+			s.justPressed[player][action] = pressed
+			s.justReleased[player][action] = released && !held
+		}
+	}
+
+	// A tap within one frame counts as held this frame, so its release is
+	// reported on the next one.
+	for key := range s.released {
+		s.released[key] = s.released[key] && s.pressed[key] && !s.down[key]
+	}
+
+	s.pressed = [glfw.KeyLast + 1]bool{}
+}
+
+func (s *State) Held(player int, action Action) bool {
+	return s.held[s.player(player, action)][action]
+}
+
+func (s *State) Pressed(player int, action Action) bool {
+	return s.justPressed[s.player(player, action)][action]
+}
+
+func (s *State) Released(player int, action Action) bool {
+	return s.justReleased[s.player(player, action)][action]
+}
+
+// AnyPressed reports whether any key at all was pressed this frame.
+func (s *State) AnyPressed() bool {
+	return s.anyPressed
+}
+
+// CapturedKey returns a key pressed this frame, for the rebinding screen.
+func (s *State) CapturedKey() (glfw.Key, bool) {
+	for key, pressed := range s.framePressed {
+		if pressed {
+			return glfw.Key(key), true
+		}
+	}
+
+	return glfw.KeyUnknown, false
+}
+
+func (s *State) player(player int, action Action) int {
+	if !action.PerPlayer() || player < 0 || player >= Players {
+		return 0
+	}
+
+	return player
+}
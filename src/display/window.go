@@ -0,0 +1,155 @@
+package display
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/go-gl/glfw/v3.3/glfw"
+
+	"breakout/src/settings"
+)
+
+// Window switches between windowed, borderless and exclusive fullscreen
+// without recreating the window, so the OpenGL context and everything loaded
+// into it survive the switch.
+type Window struct {
+	*glfw.Window
+
+	mode      settings.WindowMode
+	windowedX int
+	windowedY int
+
+	frameTime float64
+	lastFrame float64
+}
+
+// NewWindow creates a window with a current OpenGL context and applies the
+// display settings to it. The context hints have to be set before.
+func NewWindow(s *settings.Settings) (*Window, error) {
+	glfw.WindowHint(glfw.Visible, glfw.False)
+
+	window, err := glfw.CreateWindow(s.Width, s.Height, s.Title, nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create a window: %w", err)
+	}
+
+	window.MakeContextCurrent()
+
+	w := &Window{Window: window, mode: settings.Windowed}
+	w.windowedX, w.windowedY = window.GetPos()
+	w.Apply(s)
+	w.Show()
+
+	return w, nil
+}
+
+// Apply switches the window mode, monitor and video mode and sets vsync and
+// the frame rate cap. A monitor or video mode that is not available falls
+// back to the primary monitor or the desktop video mode.
+func (w *Window) Apply(s *settings.Settings) {
+	w.SetTitle(s.Title)
+
+	if w.mode == settings.Windowed {
+		w.windowedX, w.windowedY = w.GetPos()
+	}
+
+	monitor := findMonitor(s.Monitor)
+	desktop := monitor.GetVideoMode()
+
+	switch s.WindowMode {
+	case settings.Windowed:
+		w.SetMonitor(nil, w.windowedX, w.windowedY, s.Width, s.Height, 0)
+		w.SetAttrib(glfw.Decorated, glfw.True)
+	case settings.Borderless:
+		x, y := monitor.GetPos()
+		w.SetMonitor(nil, x, y, desktop.Width, desktop.Height, 0)
+		w.SetAttrib(glfw.Decorated, glfw.False)
+	case settings.Fullscreen:
+		mode := findVideoMode(monitor, s.VideoMode)
+		w.SetMonitor(monitor, 0, 0, mode.Width, mode.Height, mode.RefreshRate)
+	}
+
+	w.mode = s.WindowMode
+
+	if s.VSync {
+		glfw.SwapInterval(1)
+	} else {
+		glfw.SwapInterval(0)
+	}
+
+	w.frameTime = 0
+	if !s.VSync && s.FPSCap > 0 {
+		w.frameTime = 1 / float64(s.FPSCap)
+	}
+}
+
+// Limit sleeps out the rest of the frame while a frame rate cap applies.
+// Call it once per frame, after swapping buffers.
+func (w *Window) Limit() {
+	now := glfw.GetTime()
+	next := w.lastFrame + w.frameTime
+
+	if w.frameTime <= 0 || next <= now {
+		w.lastFrame = now
+		return
+	}
+
+	time.Sleep(time.Duration((next - now) * float64(time.Second)))
+	w.lastFrame = next
+}
+
+// Monitors names the connected monitors by their index in the settings, the
+// primary monitor is always first.
+func Monitors() []string {
+	monitors := glfw.GetMonitors()
+	names := make([]string, 0, len(monitors))
+	for _, monitor := range monitors {
+		names = append(names, monitor.GetName())
+	}
+
+	return names
+}
+
+// VideoModes lists the distinct video modes of a monitor.
+func VideoModes(index int) []settings.VideoMode {
+	var modes []settings.VideoMode
+
+	seen := make(map[settings.VideoMode]bool)
+	for _, mode := range findMonitor(index).GetVideoModes() {
+		m := settings.VideoMode{Width: mode.Width, Height: mode.Height, RefreshRate: mode.RefreshRate}
+		if !seen[m] {
+			seen[m] = true
+			modes = append(modes, m)
+		}
+	}
+
+	return modes
+}
+
+func findMonitor(index int) *glfw.Monitor {
+	monitors := glfw.GetMonitors()
+	if index >= 0 && index < len(monitors) {
+		return monitors[index]
+	}
+
+	log.Printf("monitor %d is not connected, using the primary monitor", index)
+
+	return glfw.GetPrimaryMonitor()
+}
+
+func findVideoMode(monitor *glfw.Monitor, want settings.VideoMode) *glfw.VidMode {
+	if want == (settings.VideoMode{}) {
+		return monitor.GetVideoMode()
+	}
+
+	for _, mode := range monitor.GetVideoModes() {
+		if mode.Width == want.Width && mode.Height == want.Height && mode.RefreshRate == want.RefreshRate {
+			return mode
+		}
+	}
+
+	log.Printf("monitor %s does not support video mode %s, using the desktop video mode", monitor.GetName(), want)
+
+	return monitor.GetVideoMode()
+}
@@ -10,8 +10,11 @@ type Ball struct {
 	Radius float32
 	Stuck  bool
 
-	Sticky      bool
 	PassThrough bool
+	OpenTop     bool
+	Owner       int
+
+	teleportGuard *Brick
 
 	Object
 }
@@ -20,7 +23,7 @@ func NewBall(
 	position mgl32.Vec2,
 	radius float32,
 	velocity mgl32.Vec2,
-	sprite *texture.Texture2D,
+	sprite *texture.Region,
 ) *Ball {
 	return &Ball{
 		Radius: radius,
@@ -45,7 +48,7 @@ func (b *Ball) Move(dt float64, windowWidth int) mgl32.Vec2 {
 		} else if b.Position.X()+b.Size.X() >= float32(windowWidth) {
 			b.Velocity[0] = -b.Velocity[0]
 			b.Position[0] = float32(windowWidth) - b.Size.X()
-		} else if b.Position.Y() <= 0 {
+		} else if b.Position.Y() <= 0 && !b.OpenTop {
 			b.Velocity[1] = -b.Velocity[1]
 			b.Position[1] = 0
 		}
@@ -58,4 +61,5 @@ func (b *Ball) Reset(position, velocity mgl32.Vec2) {
 	b.Position = position
 	b.Velocity = velocity
 	b.Stuck = true
+	b.teleportGuard = nil
 }
@@ -0,0 +1,222 @@
+package game
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+
+	"github.com/go-gl/mathgl/mgl32"
+
+	"breakout/src/render"
+	"breakout/src/resource"
+)
+
+type BossDef struct {
+	Texture string       `json:"texture"`
+	Color   mgl32.Vec3   `json:"color"`
+	Size    mgl32.Vec2   `json:"size"`
+	Health  int          `json:"health"`
+	Speed   float32      `json:"speed"`
+	Path    []mgl32.Vec2 `json:"path"`
+
+	Projectile struct {
+		Texture  string     `json:"texture"`
+		Color    mgl32.Vec3 `json:"color"`
+		Size     mgl32.Vec2 `json:"size"`
+		Speed    float32    `json:"speed"`
+		Interval float64    `json:"interval"`
+	} `json:"projectile"`
+
+	Minion struct {
+		Code     int     `json:"code"`
+		Interval float64 `json:"interval"`
+		Max      int     `json:"max"`
+	} `json:"minion"`
+}
+
+func LoadBossDef(fsys fs.FS, fileName string) (*BossDef, error) {
+	data, err := fs.ReadFile(fsys, fileName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read boss file: %w", err)
+	}
+
+	var def BossDef
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+
+	if err := decoder.Decode(&def); err != nil {
+		return nil, fmt.Errorf("failed to decode boss file: %w", err)
+	}
+
+	if def.Health <= 0 {
+		return nil, fmt.Errorf("boss health should be positive")
+	}
+
+	if len(def.Path) == 0 {
+		return nil, fmt.Errorf("boss path should have at least one point")
+	}
+
+	return &def, nil
+}
+
+type Boss struct {
+	Def         *BossDef
+	Health      int
+	Projectiles []Object
+
+	waypoint   int
+	fireTimer  float64
+	spawnTimer float64
+	resources  *resource.Manager
+
+	Object
+}
+
+func NewBoss(def *BossDef, resources *resource.Manager) *Boss {
+	b := &Boss{
+		Def:       def,
+		resources: resources,
+		Object: *NewObject(
+			def.Path[0],
+			def.Size,
+			resources.RegionOrFallback(def.Texture),
+			&def.Color,
+			nil,
+		),
+	}
+	b.Reset()
+
+	return b
+}
+
+func (b *Boss) Reset() {
+	b.Health = b.Def.Health
+	b.Position = b.Def.Path[0]
+	b.Destroyed = false
+	b.Projectiles = b.Projectiles[:0]
+	b.waypoint = 0
+	b.fireTimer = b.Def.Projectile.Interval
+	b.spawnTimer = b.Def.Minion.Interval
+}
+
+func (b *Boss) Move(dt float64) {
+	target := b.Def.Path[b.waypoint]
+	toTarget := target.Sub(b.Position)
+	step := b.Def.Speed * float32(dt)
+
+	if toTarget.Len() <= step {
+		b.Position = target
+		b.Velocity = mgl32.Vec2{0, 0}
+		b.waypoint = (b.waypoint + 1) % len(b.Def.Path)
+	} else {
+		b.Velocity = toTarget.Normalize().Mul(b.Def.Speed)
+		b.Position = b.Position.Add(b.Velocity.Mul(float32(dt)))
+	}
+
+	for i := range b.Projectiles {
+		b.Projectiles[i].Position = b.Projectiles[i].Position.Add(b.Projectiles[i].Velocity.Mul(float32(dt)))
+	}
+}
+
+func (b *Boss) Fire(dt float64, target mgl32.Vec2) {
+	if b.Def.Projectile.Interval <= 0 {
+		return
+	}
+
+	b.fireTimer -= dt
+	if b.fireTimer > 0 {
+		return
+	}
+
+	b.fireTimer = b.Def.Projectile.Interval
+
+	size := b.Def.Projectile.Size
+	origin := b.Position.Add(mgl32.Vec2{b.Size.X()/2 - size.X()/2, b.Size.Y()})
+	velocity := target.Sub(origin)
+	if velocity.Len() == 0 {
+		velocity = mgl32.Vec2{0, 1}
+	}
+	velocity = velocity.Normalize().Mul(b.Def.Projectile.Speed)
+
+	b.Projectiles = append(b.Projectiles, *NewObject(
+		origin,
+		size,
+		b.resources.RegionOrFallback(b.Def.Projectile.Texture),
+		&b.Def.Projectile.Color,
+		&velocity,
+	))
+}
+
+func (b *Boss) ShouldSpawnMinion(dt float64) bool {
+	if b.Def.Minion.Interval <= 0 {
+		return false
+	}
+
+	b.spawnTimer -= dt
+	if b.spawnTimer > 0 {
+		return false
+	}
+
+	b.spawnTimer = b.Def.Minion.Interval
+
+	return true
+}
+
+func (b *Boss) RemoveProjectiles(height int) {
+	moveIndex := 0
+	for i := range b.Projectiles {
+		if !b.Projectiles[i].Destroyed && b.Projectiles[i].Position.Y() < float32(height) {
+			b.Projectiles[moveIndex] = b.Projectiles[i]
+			moveIndex++
+		}
+	}
+
+	b.Projectiles = b.Projectiles[:moveIndex]
+}
+
+func (b *Boss) Hit() {
+	b.Health--
+	if b.Health <= 0 {
+		b.Health = 0
+		b.Destroyed = true
+		b.Projectiles = b.Projectiles[:0]
+	}
+}
+
+func (b *Boss) Draw(renderer *render.SpriteRenderer) {
+	if b.Destroyed {
+		return
+	}
+
+	b.Object.Draw(renderer)
+
+	for i := range b.Projectiles {
+		b.Projectiles[i].Draw(renderer)
+	}
+}
+
+func (b *Boss) DrawHealthBar(renderer *render.SpriteRenderer, width int) {
+	if b.Destroyed {
+		return
+	}
+
+	var (
+		barHeight  float32 = 10
+		barMargin  float32 = 40
+		barWidth           = float32(width) - 2*barMargin
+		fill               = barWidth * float32(b.Health) / float32(b.Def.Health)
+		position           = mgl32.Vec2{barMargin, 34}
+		background         = mgl32.Vec3{0.2, 0.2, 0.2}
+		color              = mgl32.Vec3{0.9, 0.1, 0.1}
+		tex                = b.resources.RegionOrFallback("block")
+		size               = mgl32.Vec2{barWidth, barHeight}
+		filled             = mgl32.Vec2{fill, barHeight}
+	)
+
+	renderer.Begin()
+	renderer.Draw(0, tex, position, size, 0, background)
+	renderer.Draw(1, tex, position, filled, 0, color)
+	renderer.End()
+}
@@ -0,0 +1,490 @@
+package game
+
+import (
+	"fmt"
+	"io/fs"
+	"math"
+
+	"github.com/go-gl/mathgl/mgl32"
+
+	"breakout/src/assets"
+)
+
+const (
+	botTolerance     float32 = 4
+	botMaxBounces            = 16
+	botDodgeWindow   float32 = 0.6
+	attractDelay             = 10.0
+	playtestMaxTicks         = 120 * 60 * 5
+)
+
+var botExplore = []float32{-0.8, 0.6, -0.3, 0.9, -0.6, 0.3}
+
+var powerUpPreference = map[string]int{
+	"pad-size-increase": 2,
+	"pass-through":      2,
+	"sticky":            1,
+	"speed":             -1,
+	"confuse":           -2,
+	"chaos":             -2,
+}
+
+// Autopilot drives a paddle by predicting where the ball lands and aiming
+// the deflection at the remaining bricks.
+type Autopilot struct {
+	approaches int
+	descending bool
+}
+
+func NewAutopilot() *Autopilot {
+	return &Autopilot{}
+}
+
+func (a *Autopilot) Input(g *Game, player int) Input {
+	p := g.players[player]
+	ball := g.ball
+
+	if ball.Stuck {
+		if ball.Owner == player {
+			return InputLaunch
+		}
+
+		return 0
+	}
+
+	if descending := (ball.Velocity.Y() > 0) != p.Top; descending != a.descending {
+		a.descending = descending
+		if descending {
+			a.approaches++
+		}
+	}
+
+	paddleCenter := p.Paddle.Position.X() + p.Paddle.Size.X()/2
+	target := (p.MinX + p.MaxX) / 2
+
+	landing, eta, ok := a.predictLanding(g, p)
+	if ok {
+		target = landing - a.aimOffset(g, p, landing)
+	}
+
+	if chase, ok := a.choosePowerUp(g, p, eta, ok); ok {
+		target = chase
+	}
+
+	target = mgl32.Clamp(target, p.MinX+p.Paddle.Size.X()/2, p.MaxX-p.Paddle.Size.X()/2)
+	if away, ok := a.dodge(g, p, target); ok {
+		target = away
+	}
+
+	switch {
+	case paddleCenter < target-botTolerance:
+		return InputRight
+	case paddleCenter > target+botTolerance:
+		return InputLeft
+	}
+
+	return 0
+}
+
+// predictLanding follows the ball through wall bounces until its center
+// reaches the paddle line, ignoring bricks.
+func (a *Autopilot) predictLanding(g *Game, p *Player) (float32, float32, bool) {
+	ball := g.ball
+	center := ball.Position.Add(mgl32.Vec2{ball.Radius, ball.Radius})
+	velocity := ball.Velocity
+
+	lineY := p.Paddle.Position.Y() - ball.Radius
+	if p.Top {
+		lineY = p.Paddle.Position.Y() + p.Paddle.Size.Y() + ball.Radius
+	}
+
+	var elapsed float32
+
+	for bounce := 0; bounce < botMaxBounces; bounce++ {
+		if velocity.Y() == 0 {
+			return 0, 0, false
+		}
+
+		toLine := (lineY - center.Y()) / velocity.Y()
+		if toLine < 0 {
+			if ball.OpenTop || velocity.Y() > 0 {
+				return 0, 0, false
+			}
+
+			toTop := (ball.Radius - center.Y()) / velocity.Y()
+			toWall := a.timeToWall(g, center, velocity)
+			if toWall < toTop {
+				center = center.Add(velocity.Mul(toWall))
+				velocity[0] = -velocity[0]
+				elapsed += toWall
+
+				continue
+			}
+
+			center = center.Add(velocity.Mul(toTop))
+			velocity[1] = -velocity[1]
+			elapsed += toTop
+
+			continue
+		}
+
+		toWall := a.timeToWall(g, center, velocity)
+		if toWall >= toLine {
+			return center.X() + velocity.X()*toLine, elapsed + toLine, true
+		}
+
+		center = center.Add(velocity.Mul(toWall))
+		velocity[0] = -velocity[0]
+		elapsed += toWall
+	}
+
+	return 0, 0, false
+}
+
+func (a *Autopilot) timeToWall(g *Game, center, velocity mgl32.Vec2) float32 {
+	switch {
+	case velocity.X() > 0:
+		return (float32(g.Width) - g.ball.Radius - center.X()) / velocity.X()
+	case velocity.X() < 0:
+		return (g.ball.Radius - center.X()) / velocity.X()
+	}
+
+	return float32(math.Inf(1))
+}
+
+// aimOffset returns how far from the paddle center the ball should hit so
+// the deflection from DoCollisions heads towards the nearest brick that is
+// not hidden behind a solid one. When every brick is hidden it cycles
+// through fixed angles so the ball does not repeat the same path.
+func (a *Autopilot) aimOffset(g *Game, p *Player, landing float32) float32 {
+	var (
+		level    = &g.Levels[g.Level]
+		from     = mgl32.Vec2{landing, p.Paddle.Position.Y()}
+		best     *Brick
+		bestDist float32 = math.MaxFloat32
+	)
+
+	for _, brick := range level.Bricks {
+		if brick.Destroyed || brick.IsSolid {
+			continue
+		}
+
+		center := brick.Position.Add(brick.Size.Mul(0.5))
+		dist := center.Sub(from).Len()
+		if !a.clearPath(level, from, center) {
+			dist += float32(g.Width + g.Height)
+		}
+
+		if dist < bestDist {
+			best, bestDist = brick, dist
+		}
+	}
+
+	if best == nil {
+		return 0
+	}
+
+	if bestDist > float32(g.Width+g.Height) {
+		return botExplore[a.approaches%len(botExplore)] * p.Paddle.Size.X() / 2
+	}
+
+	center := best.Position.Add(best.Size.Mul(0.5))
+	rise := float32(math.Abs(float64(from.Y() - center.Y())))
+	if rise == 0 {
+		return 0
+	}
+
+	speedY := float32(math.Abs(float64(g.ball.Velocity.Y())))
+	percentage := (center.X() - landing) / rise * speedY / (g.tuning.BallVelocity.X() * deflectStrength)
+	percentage = mgl32.Clamp(percentage, -0.9, 0.9)
+
+	return percentage * p.Paddle.Size.X() / 2
+}
+
+// clearPath reports whether the segment between from and to misses every
+// intact solid brick.
+func (a *Autopilot) clearPath(level *Level, from, to mgl32.Vec2) bool {
+	for _, brick := range level.Bricks {
+		if brick.Destroyed || !brick.IsSolid {
+			continue
+		}
+
+		if segmentHitsRect(from, to, brick.Position, brick.Position.Add(brick.Size)) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func segmentHitsRect(from, to, min, max mgl32.Vec2) bool {
+	enter, exit := float32(0), float32(1)
+	direction := to.Sub(from)
+
+	for axis := 0; axis < 2; axis++ {
+		if direction[axis] == 0 {
+			if from[axis] < min[axis] || from[axis] > max[axis] {
+				return false
+			}
+
+			continue
+		}
+
+		t1 := (min[axis] - from[axis]) / direction[axis]
+		t2 := (max[axis] - from[axis]) / direction[axis]
+		if t1 > t2 {
+			t1, t2 = t2, t1
+		}
+
+		if t1 > enter {
+			enter = t1
+		}
+		if t2 < exit {
+			exit = t2
+		}
+		if enter > exit {
+			return false
+		}
+	}
+
+	return true
+}
+
+// choosePowerUp returns a paddle target for a good power-up that can be
+// caught before the ball arrives.
+func (a *Autopilot) choosePowerUp(g *Game, p *Player, ballEta float32, ballComing bool) (float32, bool) {
+	paddleCenter := p.Paddle.Position.X() + p.Paddle.Size.X()/2
+
+	for i := range g.PowerUps {
+		pu := &g.PowerUps[i]
+		if pu.Destroyed || powerUpPreference[pu.Type] <= 0 {
+			continue
+		}
+
+		center, eta, ok := a.arrival(p, &pu.Object)
+		if !ok {
+			continue
+		}
+
+		travel := float32(math.Abs(float64(center-paddleCenter))) / g.tuning.PlayerVelocity
+		if travel < eta && (!ballComing || eta+travel < ballEta) {
+			return mgl32.Clamp(center, p.MinX, p.MaxX), true
+		}
+	}
+
+	return 0, false
+}
+
+// dodge moves the paddle out of the way of boss projectiles and unwanted
+// power-ups that would land on it at the given target.
+func (a *Autopilot) dodge(g *Game, p *Player, target float32) (float32, bool) {
+	threats := make([]*Object, 0)
+
+	for i := range g.PowerUps {
+		if !g.PowerUps[i].Destroyed && powerUpPreference[g.PowerUps[i].Type] < 0 {
+			threats = append(threats, &g.PowerUps[i].Object)
+		}
+	}
+
+	if boss := g.Levels[g.Level].Boss; boss != nil && !boss.Destroyed {
+		for i := range boss.Projectiles {
+			if !boss.Projectiles[i].Destroyed {
+				threats = append(threats, &boss.Projectiles[i])
+			}
+		}
+	}
+
+	for _, threat := range threats {
+		center, eta, ok := a.arrival(p, threat)
+		if !ok || eta > botDodgeWindow {
+			continue
+		}
+
+		sweep := threat.Velocity.X() * (p.Paddle.Size.Y() + threat.Size.Y()) / threat.Velocity.Y()
+		reach := (p.Paddle.Size.X()+threat.Size.X())/2 + float32(math.Abs(float64(sweep))) + botTolerance
+		if float32(math.Abs(float64(center-target))) >= reach {
+			continue
+		}
+
+		lo, hi := p.MinX+p.Paddle.Size.X()/2, p.MaxX-p.Paddle.Size.X()/2
+		left, right := center-reach, center+reach
+		if left < lo || (right <= hi && target > center) {
+			return right, true
+		}
+
+		return left, true
+	}
+
+	return 0, false
+}
+
+// arrival predicts the horizontal center of a falling object and how long
+// it takes to reach the paddle line, which is zero while it passes it.
+func (a *Autopilot) arrival(p *Player, obj *Object) (float32, float32, bool) {
+	if obj.Velocity.Y() == 0 || (obj.Velocity.Y() < 0) != p.Top {
+		return 0, 0, false
+	}
+
+	eta := (p.Paddle.Position.Y() - obj.Position.Y() - obj.Size.Y()) / obj.Velocity.Y()
+	if p.Top {
+		eta = (obj.Position.Y() - p.Paddle.Position.Y() - p.Paddle.Size.Y()) / -obj.Velocity.Y()
+	}
+
+	if eta < 0 {
+		if pass := (p.Paddle.Size.Y() + obj.Size.Y()) / float32(math.Abs(float64(obj.Velocity.Y()))); eta < -pass {
+			return 0, 0, false
+		}
+
+		eta = 0
+	}
+
+	return obj.Position.X() + obj.Size.X()/2 + obj.Velocity.X()*eta, eta, true
+}
+
+// newBoard creates a headless game reading fsys, or the embedded resources
+// when fsys is nil. A manifest loaded by an earlier board is reused.
+func newBoard(fsys fs.FS, manifest *assets.Manifest, width, height int) (*Game, error) {
+	g := NewGame(width, height)
+	if fsys != nil {
+		g.Assets = fsys
+	}
+	g.manifest = manifest
+
+	if err := g.InitSimulation(); err != nil {
+		return nil, fmt.Errorf("failed to init simulation: %w", err)
+	}
+
+	return g, nil
+}
+
+type PlaytestReport struct {
+	Level         int
+	Cleared       bool
+	Ticks         uint64
+	LivesLost     int
+	BricksLeft    int
+	BricksTotal   int
+	PowerUpsTaken int
+	BossHealth    int
+}
+
+func (r PlaytestReport) String() string {
+	result := "failed"
+	if r.Cleared {
+		result = "cleared"
+	}
+
+	report := fmt.Sprintf(
+		"level %d: %s in %.1fs, lives lost %d, bricks left %d/%d, power-ups %d",
+		r.Level+1, result, float64(r.Ticks)*tickDuration, r.LivesLost, r.BricksLeft, r.BricksTotal, r.PowerUpsTaken,
+	)
+	if r.BossHealth > 0 {
+		report += fmt.Sprintf(", boss health %d", r.BossHealth)
+	}
+
+	return report
+}
+
+// Playtest runs a level headlessly with the autopilot until it is cleared,
+// the bot runs out of lives or maxTicks pass.
+func Playtest(fsys fs.FS, width, height, level int, seed int64, maxTicks uint64) (PlaytestReport, error) {
+	g, err := newBoard(fsys, nil, width, height)
+	if err != nil {
+		return PlaytestReport{Level: level}, err
+	}
+
+	return g.playtest(level, seed, maxTicks)
+}
+
+// PlaytestLevels plays every level in turn, each on a fresh board, and
+// hands each report to report as soon as the level is over.
+func PlaytestLevels(fsys fs.FS, width, height int, seed int64, maxTicks uint64, report func(PlaytestReport)) error {
+	g, err := newBoard(fsys, nil, width, height)
+	if err != nil {
+		return err
+	}
+
+	levels := len(g.Levels)
+	for level := 0; level < levels; level++ {
+		if level > 0 {
+			if g, err = newBoard(fsys, g.manifest, width, height); err != nil {
+				return err
+			}
+		}
+
+		r, err := g.playtest(level, seed, maxTicks)
+		if err != nil {
+			return err
+		}
+
+		report(r)
+	}
+
+	return nil
+}
+
+func (g *Game) playtest(level int, seed int64, maxTicks uint64) (PlaytestReport, error) {
+	report := PlaytestReport{Level: level}
+
+	if level < 0 || level >= len(g.Levels) {
+		return report, fmt.Errorf("unknown level %d", level)
+	}
+
+	if maxTicks == 0 {
+		maxTicks = playtestMaxTicks
+	}
+
+	g.Seed(seed)
+	g.Level = level
+	g.ResetLevel()
+	g.ResetScores()
+	g.ResetPlayers(0)
+	g.bots[0] = NewAutopilot()
+	g.State = StateActive
+
+	done := false
+	g.OnEvent(func(e Event) {
+		switch e.Type {
+		case EventLifeLost:
+			report.LivesLost++
+			if g.players[0].Lives == 0 {
+				done = true
+			}
+		case EventPowerUpCollected:
+			report.PowerUpsTaken++
+		case EventLevelCompleted:
+			report.Cleared = true
+			done = true
+		}
+	})
+
+	for _, brick := range g.Levels[level].Bricks {
+		if !brick.IsSolid {
+			report.BricksTotal++
+		}
+	}
+
+	for !done && report.Ticks < maxTicks {
+		report.Ticks++
+		g.step(tickDuration)
+
+		if !done {
+			report.BricksLeft = 0
+			for _, brick := range g.Levels[level].Bricks {
+				if !brick.IsSolid && !brick.Destroyed && !brick.Spawned {
+					report.BricksLeft++
+				}
+			}
+
+			if boss := g.Levels[level].Boss; boss != nil {
+				report.BossHealth = boss.Health
+			}
+		}
+	}
+
+	if report.Cleared {
+		report.BricksLeft, report.BossHealth = 0, 0
+	}
+
+	return report, nil
+}
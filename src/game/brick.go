@@ -0,0 +1,45 @@
+package game
+
+import "github.com/go-gl/mathgl/mgl32"
+
+type BrickKind int
+
+const (
+	NormalBrick BrickKind = iota
+	SolidBrick
+	TeleporterBrick
+	SwitchBrick
+	DoorBrick
+	OneWayBrick
+)
+
+type Brick struct {
+	Kind   BrickKind
+	Link   int
+	Motion *Motion
+	Origin mgl32.Vec2
+	Row    int
+
+	Spawned bool
+
+	Object
+}
+
+func NewBrick(kind BrickKind, link int, object *Object) *Brick {
+	object.IsSolid = kind != NormalBrick && kind != OneWayBrick
+
+	return &Brick{
+		Kind:   kind,
+		Link:   link,
+		Origin: object.Position,
+		Object: *object,
+	}
+}
+
+func (b *Brick) Blocks(velocity mgl32.Vec2) bool {
+	if b.Kind == OneWayBrick {
+		return velocity.Y() > 0
+	}
+
+	return true
+}
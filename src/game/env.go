@@ -0,0 +1,485 @@
+package game
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"math"
+
+	"github.com/go-gl/mathgl/mgl32"
+
+	"breakout/resources"
+	"breakout/src/assets"
+)
+
+type Action int
+
+const (
+	ActionNone Action = iota
+	ActionLeft
+	ActionRight
+	ActionLaunch
+	actionCount
+)
+
+func (a Action) String() string {
+	switch a {
+	case ActionNone:
+		return "none"
+	case ActionLeft:
+		return "left"
+	case ActionRight:
+		return "right"
+	case ActionLaunch:
+		return "launch"
+	}
+
+	return "unknown"
+}
+
+type ObservationKind int
+
+const (
+	ObserveState ObservationKind = iota
+	ObservePixels
+)
+
+const (
+	observedPowerUps = 4
+	defaultFrameSkip = 4
+	defaultPixelSize = 8
+)
+
+var (
+	ErrEpisodeDone = errors.New("episode is done, call Reset")
+
+	observedPowerUpTypes = []string{"speed", "sticky", "pass-through", "pad-size-increase", "confuse", "chaos"}
+)
+
+// StepInfo describes what happened during one environment step. Reward
+// functions get it after the step has been simulated.
+type StepInfo struct {
+	Events      []Event
+	Ticks       uint64
+	Score       int
+	ScoreGained int
+	Lives       uint32
+	BricksLeft  int
+	Cleared     bool
+	GameOver    bool
+	TimedOut    bool
+}
+
+type RewardFunc func(info StepInfo) float64
+
+// DefaultReward pays for destroyed bricks and boss hits and punishes lost
+// lives, with a bonus for clearing the level.
+func DefaultReward(info StepInfo) float64 {
+	var reward float64
+
+	for _, e := range info.Events {
+		switch e.Type {
+		case EventBrickDestroyed:
+			reward += 1
+		case EventBossHit:
+			reward += 2
+		case EventLifeLost:
+			reward -= 5
+		case EventLevelCompleted:
+			reward += 20
+		}
+	}
+
+	return reward
+}
+
+// ScoreReward pays the score gained during the step.
+func ScoreReward(info StepInfo) float64 {
+	return float64(info.ScoreGained)
+}
+
+// SurvivalReward pays for every paddle hit and punishes lost lives, which
+// trains keeping the ball in play before breaking bricks.
+func SurvivalReward(info StepInfo) float64 {
+	var reward float64
+
+	for _, e := range info.Events {
+		switch e.Type {
+		case EventPaddleHit:
+			reward += 1
+		case EventLifeLost:
+			reward -= 1
+		}
+	}
+
+	return reward
+}
+
+type EnvConfig struct {
+	Width       int
+	Height      int
+	Observation ObservationKind
+	// PixelSize is the side of the square of screen pixels averaged into
+	// one pixel observation.
+	PixelSize int
+	// FrameSkip is how many simulation ticks one step repeats its action.
+	FrameSkip int
+	MaxTicks  uint64
+	Reward    RewardFunc
+	// Assets replaces the embedded resources when set.
+	Assets fs.FS
+}
+
+// Environment wraps a headless single-player game behind a reset/step
+// interface for training agents.
+type Environment struct {
+	config   EnvConfig
+	manifest *assets.Manifest
+	game     *Game
+	events   []Event
+	ticks    uint64
+	done     bool
+	cleared  bool
+	gameOver bool
+
+	// final holds the board as it was when the episode ended, before the
+	// game reset it.
+	final            StepInfo
+	finalObservation []float32
+}
+
+func NewEnvironment(config EnvConfig) *Environment {
+	if config.PixelSize <= 0 {
+		config.PixelSize = defaultPixelSize
+	}
+
+	if config.FrameSkip <= 0 {
+		config.FrameSkip = defaultFrameSkip
+	}
+
+	if config.MaxTicks == 0 {
+		config.MaxTicks = playtestMaxTicks
+	}
+
+	if config.Reward == nil {
+		config.Reward = DefaultReward
+	}
+
+	return &Environment{config: config, done: true}
+}
+
+func (e *Environment) Config() EnvConfig {
+	return e.config
+}
+
+// LevelCount is the number of levels in the manifest, which is loaded once
+// and shared by every board of the environment.
+func (e *Environment) LevelCount() (int, error) {
+	if e.manifest == nil {
+		fsys := e.config.Assets
+		if fsys == nil {
+			fsys = resources.FS
+		}
+
+		m, err := assets.LoadManifest(fsys, ManifestFile)
+		if err != nil {
+			return 0, fmt.Errorf("failed to load manifest: %w", err)
+		}
+
+		e.manifest = m
+	}
+
+	return len(e.manifest.Levels), nil
+}
+
+// Reset starts a new episode on a fresh board so nothing carries over from
+// the previous one.
+func (e *Environment) Reset(seed int64, level int) ([]float32, error) {
+	g, err := newBoard(e.config.Assets, e.manifest, e.config.Width, e.config.Height)
+	if err != nil {
+		return nil, err
+	}
+	e.manifest = g.manifest
+
+	if level < 0 || level >= len(g.Levels) {
+		return nil, fmt.Errorf("unknown level %d", level)
+	}
+
+	g.Seed(seed)
+	g.Level = level
+	g.ResetLevel()
+	g.ResetScores()
+	g.ResetPlayers(0)
+	g.State = StateActive
+	g.OnEvent(func(event Event) {
+		e.events = append(e.events, event)
+
+		switch {
+		case event.Type == EventLevelCompleted:
+			e.cleared = true
+		case event.Type == EventLifeLost && g.players[0].Lives == 0:
+			e.gameOver = true
+		default:
+			return
+		}
+
+		e.final = StepInfo{
+			Score:      g.players[0].Score,
+			Lives:      g.players[0].Lives,
+			BricksLeft: e.bricksLeft(),
+		}
+		e.finalObservation = e.Observe()
+	})
+
+	e.game = g
+	e.events = e.events[:0]
+	e.ticks = 0
+	e.done = false
+	e.cleared = false
+	e.gameOver = false
+
+	return e.Observe(), nil
+}
+
+// Step repeats the action for FrameSkip ticks and returns the observation
+// after them, the shaped reward and whether the episode ended.
+func (e *Environment) Step(action Action) ([]float32, float64, bool, StepInfo, error) {
+	if e.done {
+		return nil, 0, true, StepInfo{}, ErrEpisodeDone
+	}
+
+	if action < 0 || action >= actionCount {
+		return nil, 0, false, StepInfo{}, fmt.Errorf("unknown action %d", action)
+	}
+
+	e.events = e.events[:0]
+
+	var input Input
+	switch action {
+	case ActionLeft:
+		input = InputLeft
+	case ActionRight:
+		input = InputRight
+	case ActionLaunch:
+		input = InputLaunch
+	}
+
+	p := e.game.players[0]
+	score := p.Score
+
+	for i := 0; i < e.config.FrameSkip && e.game.State == StateActive; i++ {
+		e.game.Tick(input)
+		e.ticks++
+	}
+
+	info := StepInfo{
+		Events:     append([]Event(nil), e.events...),
+		Ticks:      e.ticks,
+		Score:      p.Score,
+		Lives:      p.Lives,
+		BricksLeft: e.bricksLeft(),
+		Cleared:    e.cleared,
+		GameOver:   e.gameOver,
+		TimedOut:   e.ticks >= e.config.MaxTicks,
+	}
+
+	observation := e.Observe()
+	if e.cleared || e.gameOver {
+		info.Score, info.Lives, info.BricksLeft = e.final.Score, e.final.Lives, e.final.BricksLeft
+		observation = e.finalObservation
+	}
+
+	info.ScoreGained = info.Score - score
+	e.done = info.Cleared || info.GameOver || info.TimedOut
+
+	return observation, e.config.Reward(info), e.done, info, nil
+}
+
+func (e *Environment) bricksLeft() int {
+	left := 0
+	for _, brick := range e.game.Levels[e.game.Level].Bricks {
+		if !brick.IsSolid && !brick.Destroyed {
+			left++
+		}
+	}
+
+	return left
+}
+
+// ObservationShape returns the observation dimensions, height and width for
+// pixels or the vector length for state observations.
+func (e *Environment) ObservationShape() []int {
+	if e.config.Observation == ObservePixels {
+		return []int{e.config.Height / e.config.PixelSize, e.config.Width / e.config.PixelSize}
+	}
+
+	return []int{len(e.Observe())}
+}
+
+func (e *Environment) Observe() []float32 {
+	if e.game == nil {
+		return nil
+	}
+
+	if e.config.Observation == ObservePixels {
+		return e.observePixels()
+	}
+
+	return e.observeState()
+}
+
+// observeState lays out the ball, the paddle, active effects, the boss, a
+// fixed number of falling power-ups and one flag per brick of the level, all
+// scaled to roughly [-1, 1].
+func (e *Environment) observeState() []float32 {
+	var (
+		g      = e.game
+		p      = g.players[0]
+		level  = &g.Levels[g.Level]
+		width  = float32(g.Width)
+		height = float32(g.Height)
+		speed  = g.tuning.BallVelocity.Len()
+	)
+
+	state := []float32{
+		(g.ball.Position.X() + g.ball.Radius) / width,
+		(g.ball.Position.Y() + g.ball.Radius) / height,
+		g.ball.Velocity.X() / speed,
+		g.ball.Velocity.Y() / speed,
+		boolFloat(g.ball.Stuck),
+		boolFloat(g.ball.PassThrough),
+		(p.Paddle.Position.X() + p.Paddle.Size.X()/2) / width,
+		p.Paddle.Size.X() / width,
+		boolFloat(p.Sticky),
+		boolFloat(g.confuse),
+		boolFloat(g.chaos),
+	}
+
+	if boss := level.Boss; boss != nil && !boss.Destroyed {
+		state = append(state,
+			(boss.Position.X()+boss.Size.X()/2)/width,
+			(boss.Position.Y()+boss.Size.Y()/2)/height,
+			float32(boss.Health)/float32(boss.Def.Health),
+		)
+	} else {
+		state = append(state, 0, 0, 0)
+	}
+
+	observed := 0
+	for i := range g.PowerUps {
+		pu := &g.PowerUps[i]
+		if pu.Destroyed || observed == observedPowerUps {
+			continue
+		}
+
+		state = append(state,
+			(pu.Position.X()+pu.Size.X()/2)/width,
+			(pu.Position.Y()+pu.Size.Y()/2)/height,
+			float32(powerUpIndex(pu.Type)+1)/float32(len(observedPowerUpTypes)),
+		)
+		observed++
+	}
+
+	for ; observed < observedPowerUps; observed++ {
+		state = append(state, 0, 0, 0)
+	}
+
+	for _, brick := range level.Bricks {
+		if brick.Spawned {
+			continue
+		}
+
+		state = append(state, boolFloat(!brick.Destroyed))
+	}
+
+	return state
+}
+
+// observePixels rasterizes the board on the CPU into a grayscale grid where
+// each cell is the coverage-weighted brightness of PixelSize^2 screen pixels.
+func (e *Environment) observePixels() []float32 {
+	g := e.game
+	size := float32(e.config.PixelSize)
+	columns, rows := g.Width/e.config.PixelSize, g.Height/e.config.PixelSize
+	pixels := make([]float32, columns*rows)
+
+	fill := func(o *Object, brightness float32) {
+		minX, minY := o.Position.X()/size, o.Position.Y()/size
+		maxX, maxY := (o.Position.X()+o.Size.X())/size, (o.Position.Y()+o.Size.Y())/size
+
+		for y := int(math.Max(0, math.Floor(float64(minY)))); y < rows && float32(y) < maxY; y++ {
+			for x := int(math.Max(0, math.Floor(float64(minX)))); x < columns && float32(x) < maxX; x++ {
+				coverage := overlap(float32(x), minX, maxX) * overlap(float32(y), minY, maxY)
+				pixels[y*columns+x] = mgl32.Clamp(pixels[y*columns+x]+coverage*brightness, 0, 1)
+			}
+		}
+	}
+
+	level := &g.Levels[g.Level]
+	for _, brick := range level.Bricks {
+		if brick.Destroyed {
+			continue
+		}
+
+		brightness := float32(0.4)
+		if brick.IsSolid {
+			brightness = 0.7
+		}
+
+		fill(&brick.Object, brightness)
+	}
+
+	if boss := level.Boss; boss != nil && !boss.Destroyed {
+		fill(&boss.Object, 0.7)
+
+		for i := range boss.Projectiles {
+			fill(&boss.Projectiles[i], 0.9)
+		}
+	}
+
+	for i := range g.PowerUps {
+		if !g.PowerUps[i].Destroyed {
+			fill(&g.PowerUps[i].Object, 0.6)
+		}
+	}
+
+	fill(g.players[0].Paddle, 1)
+	fill(&g.ball.Object, 1)
+
+	return pixels
+}
+
+// overlap returns how much of the unit cell starting at cell lies in
+// [from, to].
+func overlap(cell, from, to float32) float32 {
+	lo, hi := cell, cell+1
+	if from > lo {
+		lo = from
+	}
+	if to < hi {
+		hi = to
+	}
+	if hi < lo {
+		return 0
+	}
+
+	return hi - lo
+}
+
+func powerUpIndex(t string) int {
+	for i, name := range observedPowerUpTypes {
+		if name == t {
+			return i
+		}
+	}
+
+	return -1
+}
+
+func boolFloat(b bool) float32 {
+	if b {
+		return 1
+	}
+
+	return 0
+}
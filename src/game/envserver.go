@@ -0,0 +1,192 @@
+package game
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+)
+
+var rewardFuncs = map[string]RewardFunc{
+	"default":  DefaultReward,
+	"score":    ScoreReward,
+	"survival": SurvivalReward,
+}
+
+type envRequest struct {
+	Cmd string `json:"cmd"`
+
+	Seed   int64  `json:"seed"`
+	Level  int    `json:"level"`
+	Action Action `json:"action"`
+
+	Observation string `json:"observation"`
+	PixelSize   int    `json:"pixel_size"`
+	FrameSkip   int    `json:"frame_skip"`
+	MaxTicks    uint64 `json:"max_ticks"`
+	Reward      string `json:"reward"`
+}
+
+type envInfo struct {
+	Ticks      uint64   `json:"ticks"`
+	Score      int      `json:"score"`
+	Lives      uint32   `json:"lives"`
+	BricksLeft int      `json:"bricks_left"`
+	Cleared    bool     `json:"cleared"`
+	GameOver   bool     `json:"game_over"`
+	TimedOut   bool     `json:"timed_out"`
+	Events     []string `json:"events"`
+}
+
+type envResponse struct {
+	Error string `json:"error,omitempty"`
+
+	Actions []string `json:"actions,omitempty"`
+	Levels  int      `json:"levels,omitempty"`
+
+	Observation []float32 `json:"observation,omitempty"`
+	Shape       []int     `json:"shape,omitempty"`
+	Reward      float64   `json:"reward"`
+	Done        bool      `json:"done"`
+	Info        *envInfo  `json:"info,omitempty"`
+}
+
+// ServeEnvironment drives an Environment with one JSON request per line on r
+// and answers each with one JSON line on w, until a close request or EOF.
+//
+//	{"cmd": "configure", "observation": "pixels", "pixel_size": 8, "frame_skip": 4, "reward": "score"}
+//	{"cmd": "reset", "seed": 1, "level": 0}
+//	{"cmd": "step", "action": 2}
+//	{"cmd": "close"}
+func ServeEnvironment(width, height int, fsys fs.FS, r io.Reader, w io.Writer) error {
+	s := &envServer{env: NewEnvironment(EnvConfig{Width: width, Height: height, Assets: fsys})}
+
+	scanner := bufio.NewScanner(r)
+	writer := bufio.NewWriter(w)
+	encoder := json.NewEncoder(writer)
+
+	for scanner.Scan() {
+		var req envRequest
+		if err := json.Unmarshal(scanner.Bytes(), &req); err != nil {
+			if err := respond(writer, encoder, envResponse{Error: fmt.Sprintf("invalid request: %v", err)}); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		if req.Cmd == "close" {
+			return respond(writer, encoder, envResponse{})
+		}
+
+		resp, err := s.handle(req)
+		if err != nil {
+			resp = envResponse{Error: err.Error()}
+		}
+
+		if err := respond(writer, encoder, resp); err != nil {
+			return err
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read request: %w", err)
+	}
+
+	return nil
+}
+
+type envServer struct {
+	env *Environment
+}
+
+func (s *envServer) handle(req envRequest) (envResponse, error) {
+	switch req.Cmd {
+	case "configure":
+		config := s.env.Config()
+		config.PixelSize = req.PixelSize
+		config.FrameSkip = req.FrameSkip
+		config.MaxTicks = req.MaxTicks
+
+		switch req.Observation {
+		case "", "state":
+			config.Observation = ObserveState
+		case "pixels":
+			config.Observation = ObservePixels
+		default:
+			return envResponse{}, fmt.Errorf("unknown observation %q", req.Observation)
+		}
+
+		config.Reward = rewardFuncs["default"]
+		if req.Reward != "" {
+			reward, ok := rewardFuncs[req.Reward]
+			if !ok {
+				return envResponse{}, fmt.Errorf("unknown reward %q", req.Reward)
+			}
+
+			config.Reward = reward
+		}
+
+		s.env = NewEnvironment(config)
+
+		actions := make([]string, 0, actionCount)
+		for a := ActionNone; a < actionCount; a++ {
+			actions = append(actions, a.String())
+		}
+
+		levels, err := s.env.LevelCount()
+		if err != nil {
+			return envResponse{}, err
+		}
+
+		return envResponse{Actions: actions, Levels: levels}, nil
+	case "reset":
+		observation, err := s.env.Reset(req.Seed, req.Level)
+		if err != nil {
+			return envResponse{}, fmt.Errorf("failed to reset: %w", err)
+		}
+
+		return envResponse{Observation: observation, Shape: s.env.ObservationShape()}, nil
+	case "step":
+		observation, reward, done, info, err := s.env.Step(req.Action)
+		if err != nil {
+			return envResponse{}, fmt.Errorf("failed to step: %w", err)
+		}
+
+		events := make([]string, 0, len(info.Events))
+		for _, e := range info.Events {
+			events = append(events, e.Type.String())
+		}
+
+		return envResponse{
+			Observation: observation,
+			Reward:      reward,
+			Done:        done,
+			Info: &envInfo{
+				Ticks:      info.Ticks,
+				Score:      info.Score,
+				Lives:      info.Lives,
+				BricksLeft: info.BricksLeft,
+				Cleared:    info.Cleared,
+				GameOver:   info.GameOver,
+				TimedOut:   info.TimedOut,
+				Events:     events,
+			},
+		}, nil
+	}
+
+	return envResponse{}, fmt.Errorf("unknown command %q", req.Cmd)
+}
+
+func respond(writer *bufio.Writer, encoder *json.Encoder, resp envResponse) error {
+	if err := encoder.Encode(resp); err != nil {
+		return fmt.Errorf("failed to write response: %w", err)
+	}
+
+	if err := writer.Flush(); err != nil {
+		return fmt.Errorf("failed to write response: %w", err)
+	}
+
+	return nil
+}
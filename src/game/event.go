@@ -0,0 +1,85 @@
+package game
+
+import "github.com/go-gl/mathgl/mgl32"
+
+type EventType int
+
+const (
+	EventBrickDestroyed EventType = iota
+	EventSolidHit
+	EventPaddleHit
+	EventPowerUpCollected
+	EventLifeLost
+	EventBossHit
+	EventLevelCompleted
+)
+
+func (t EventType) String() string {
+	switch t {
+	case EventBrickDestroyed:
+		return "brick_destroyed"
+	case EventSolidHit:
+		return "solid_hit"
+	case EventPaddleHit:
+		return "paddle_hit"
+	case EventPowerUpCollected:
+		return "powerup_collected"
+	case EventLifeLost:
+		return "life_lost"
+	case EventBossHit:
+		return "boss_hit"
+	case EventLevelCompleted:
+		return "level_completed"
+	}
+
+	return "unknown"
+}
+
+type Event struct {
+	Type     EventType
+	Tick     uint64
+	Player   int
+	Position mgl32.Vec2
+	Color    mgl32.Vec3
+	Name     string
+}
+
+func (g *Game) OnEvent(listener func(Event)) {
+	g.onEvent = append(g.onEvent, listener)
+}
+
+func (g *Game) OnTick(listener func()) {
+	g.onTick = append(g.onTick, listener)
+}
+
+func (g *Game) emit(e Event) {
+	e.Tick = g.tick
+
+	for _, listener := range g.onEvent {
+		listener(e)
+	}
+}
+
+func (g *Game) burstParticles(e Event) {
+	switch e.Type {
+	case EventBrickDestroyed:
+		g.Particles.Burst("brick", e.Position, e.Color)
+	case EventPaddleHit:
+		g.Particles.Burst("paddle", e.Position, mgl32.Vec3{1, 1, 1})
+	case EventPowerUpCollected:
+		g.Particles.Burst("powerup", e.Position, e.Color)
+	}
+}
+
+func (g *Game) playEventSound(e Event) {
+	switch e.Type {
+	case EventBrickDestroyed:
+		g.soundsPlayer.Play("brick")
+	case EventSolidHit, EventBossHit:
+		g.soundsPlayer.Play("solid")
+	case EventPaddleHit:
+		g.soundsPlayer.Play("paddle")
+	case EventPowerUpCollected:
+		g.soundsPlayer.Play("powerup")
+	}
+}
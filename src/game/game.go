@@ -2,15 +2,24 @@ package game
 
 import (
 	"fmt"
+	"io/fs"
+	"log"
 	"math"
 	"math/rand"
+	"time"
 
 	"github.com/go-gl/glfw/v3.3/glfw"
 	"github.com/go-gl/mathgl/mgl32"
 
+	"breakout/resources"
+	"breakout/src/assets"
+	"breakout/src/controls"
 	"breakout/src/render"
 	"breakout/src/resource"
+	"breakout/src/settings"
+	"breakout/src/shader"
 	"breakout/src/sound"
+	"breakout/src/ui"
 )
 
 type State int
@@ -19,88 +28,226 @@ const (
 	StateActive State = iota
 	StateMenu
 	StateWin
+	StateDemo
+	StateControls
+	StateSettings
+	StateLevelSelect
 
-	playerVelocity float32 = 500
-	ballRadius     float32 = 12.5
-	particleAmount         = 2000
+	brickScore = 10
+	bossScore  = 50
+	anyPlayer  = -1
 
-	startingLives = 3
-)
+	// ManifestFile lists the assets, it is read from Assets.
+	ManifestFile = "manifest.json"
+	uiFont       = "ui"
 
-var (
-	playerSize       = mgl32.Vec2{100, 20}
-	initBallVelocity = mgl32.Vec2{100, -350}
-	shaderFiles      = map[string]struct {
-		v, f, g string
-	}{
-		"sprite":         {"resources/shaders/sprite.vert", "resources/shaders/sprite.frag", ""},
-		"particle":       {"resources/shaders/particle.vert", "resources/shaders/particle.frag", ""},
-		"postprocessing": {"resources/shaders/postprocessing.vert", "resources/shaders/postprocessing.frag", ""},
-	}
-	textureFiles = map[string]struct {
-		path  string
-		alpha bool
-	}{
-		"background":          {"resources/textures/background.png", false},
-		"face":                {"resources/textures/happy.png", true},
-		"block":               {"resources/textures/block.png", false},
-		"block_solid":         {"resources/textures/block_solid.png", false},
-		"paddle":              {"resources/textures/paddle.png", true},
-		"particle":            {"resources/textures/particle.png", true},
-		"powerup_chaos":       {"resources/textures/powerup_chaos.png", true},
-		"powerup_confuse":     {"resources/textures/powerup_confuse.png", true},
-		"powerup_increase":    {"resources/textures/powerup_increase.png", true},
-		"powerup_passthrough": {"resources/textures/powerup_passthrough.png", true},
-		"powerup_speed":       {"resources/textures/powerup_speed.png", true},
-		"powerup_sticky":      {"resources/textures/powerup_sticky.png", true},
-	}
-	levelFiles = []string{
-		"resources/levels/one.lvl",
-		"resources/levels/two.lvl",
-		"resources/levels/three.lvl",
-		"resources/levels/four.lvl",
-	}
-	fontFiles = map[string]int{
-		"resources/fonts/ocraext.ttf": 24,
-	}
+	tickDuration = 1.0 / 120
+	maxFrameTime = 0.25
+
+	// deflectStrength scales how far off the paddle centre sends the ball
+	// sideways, the autopilot predicts landings with it too.
+	deflectStrength float32 = 2
+
+	mouseDeadband = 1
+	mouseResponse = 4 * tickDuration
 )
 
+var playerSize = mgl32.Vec2{100, 20}
+
 type Game struct {
-	State         State
-	Keys          [1024]bool
-	KeysProcessed [1024]bool
-	Width         int
-	Height        int
+	State    State
+	Mode     Mode
+	Controls *controls.State
+
+	// Width and Height are the size of the virtual canvas the game is
+	// simulated and drawn in, the viewport scales it to the framebuffer.
+	Width  int
+	Height int
+
+	// Settings are the options the game was started with, SettingsFile is
+	// where the settings and controls screens save them.
+	Settings     *settings.Settings
+	SettingsFile string
 
 	Levels []Level
 	Level  int
 
 	PowerUps []PowerUp
 
+	// Assets is where every game file is read from, the embedded resources
+	// unless it is replaced before Init.
+	Assets fs.FS
+
+	// Resources is nil for headless games, which draw nothing.
+	Resources *resource.Manager
+	manifest  *assets.Manifest
 	Renderer  *render.SpriteRenderer
 	Effects   *render.PostProcessor
 	Text      *render.TextRenderer
-	Particles *ParticleGenerator
+	Particles *ParticleSystem
 
 	ball       *Ball
-	player     *Object
+	players    []*Player
 	background *Object
-	lives      uint32
 
 	soundsPlayer *sound.Player
-
+	onEvent      []func(Event)
+	onTick       []func()
+	onSettings   []func(*settings.Settings)
+
+	tuning settings.Gameplay
+	rng    *rand.Rand
+	tick   uint64
+	inputs [2]Input
+	bots   [2]*Autopilot
+	match  *Match
+
+	confuse   bool
+	chaos     bool
+	shaking   bool
 	shakeTime float64
+
+	viewport    render.Viewport
+	framebuffer [2]int
+	window      [2]int
+
+	accumulator float64
+	idleTime    float64
+	paused      bool
+	rebind      rebindScreen
+	options     settingsScreen
+	hotReload   *hotReload
+
+	// ui is nil for headless games. screen is rebuilt when the game moves
+	// to another menu screen.
+	ui          *ui.Context
+	screen      *ui.Screen
+	screenShown screenKind
 }
 
 func NewGame(width, height int) *Game {
-	return &Game{
-		State:  StateMenu,
-		Width:  width,
-		Height: height,
+	g := &Game{
+		State:    StateMenu,
+		Controls: controls.NewState(controls.DefaultBindings()),
+		Width:    width,
+		Height:   height,
+		Assets:   resources.FS,
+		rng:      rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+	g.ApplySettings(settings.Default())
+
+	return g
+}
+
+// ApplySettings takes over the controls, volumes, gameplay tuning and render
+// settings and passes the settings on to the OnSettingsChanged listeners.
+// The particle pool size only takes effect on the next start.
+func (g *Game) ApplySettings(s *settings.Settings) {
+	g.Settings = s
+	g.Controls.SetBindings(s.Bindings)
+	g.Controls.Mouse = s.Mouse
+
+	if g.match == nil {
+		g.setTuning(s.Gameplay)
+	}
+
+	if limit := g.tuning.PlayerVelocity * inputSpeedRange; g.Controls.Mouse.MaxSpeed > limit {
+		log.Printf("warning: mouse-max-speed %g is above %g times the paddle speed, using %g",
+			g.Controls.Mouse.MaxSpeed, float32(inputSpeedRange), limit)
+		g.Controls.Mouse.MaxSpeed = limit
+	}
+
+	if g.soundsPlayer != nil {
+		g.soundsPlayer.SetVolume(s.MasterVolume*s.MusicVolume, s.MasterVolume*s.EffectsVolume)
+	}
+
+	if g.Effects != nil {
+		if err := g.Effects.SetSamples(s.Samples); err != nil {
+			log.Println("failed to change MSAA samples:", err)
+		}
+	}
+
+	if err := g.Resize(g.framebuffer[0], g.framebuffer[1], g.window[0], g.window[1]); err != nil {
+		log.Println("failed to resize:", err)
+	}
+
+	for _, listener := range g.onSettings {
+		listener(s)
+	}
+}
+
+// OnSettingsChanged registers a listener for settings applied at runtime,
+// for example the window applying display settings.
+func (g *Game) OnSettingsChanged(listener func(*settings.Settings)) {
+	g.onSettings = append(g.onSettings, listener)
+}
+
+// Resize fits the canvas into a framebuffer of the given size in pixels. On
+// HiDPI displays the window size in screen coordinates is smaller, it maps
+// the cursor onto the canvas.
+func (g *Game) Resize(framebufferWidth, framebufferHeight, windowWidth, windowHeight int) error {
+	if framebufferWidth <= 0 || framebufferHeight <= 0 || windowWidth <= 0 || windowHeight <= 0 {
+		return nil
+	}
+
+	g.framebuffer = [2]int{framebufferWidth, framebufferHeight}
+	g.window = [2]int{windowWidth, windowHeight}
+	g.viewport = render.FitViewport(g.Width, g.Height, framebufferWidth, framebufferHeight, g.Settings.IntegerScaling)
+
+	scaleX := float64(windowWidth) / float64(framebufferWidth)
+	scaleY := float64(windowHeight) / float64(framebufferHeight)
+	top := framebufferHeight - int(g.viewport.Y+g.viewport.Height)
+	g.Controls.SetViewport(
+		float64(g.viewport.X)*scaleX,
+		float64(top)*scaleY,
+		float64(g.viewport.Width)*scaleX,
+		float64(g.viewport.Height)*scaleY,
+	)
+
+	if g.Effects != nil {
+		if err := g.Effects.Resize(int(g.viewport.Width), int(g.viewport.Height)); err != nil {
+			return fmt.Errorf("failed to resize post processor: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func (g *Game) setTuning(tuning settings.Gameplay) {
+	g.tuning = tuning
+
+	if g.ball != nil {
+		g.ball.Radius = tuning.BallRadius
+		g.ball.Size = mgl32.Vec2{tuning.BallRadius * 2, tuning.BallRadius * 2}
+	}
+}
+
+// saveSettings writes the current settings, including keys rebound on the
+// controls screen and the mouse toggle, back to the settings file.
+func (g *Game) saveSettings() {
+	g.Settings.Bindings = g.Controls.Bindings()
+	g.Settings.Mouse = g.Controls.Mouse
+
+	if g.SettingsFile == "" {
+		return
+	}
+
+	if err := g.Settings.Save(g.SettingsFile); err != nil {
+		log.Println("failed to save settings:", err)
 	}
 }
 
+func (g *Game) Seed(seed int64) {
+	g.rng.Seed(seed)
+}
+
 func (g *Game) Cleanup() error {
+	if g.match != nil {
+		if err := g.match.Close(); err != nil {
+			return fmt.Errorf("failed to close match: %w", err)
+		}
+	}
+
 	if g.Renderer != nil {
 		g.Renderer.Cleanup()
 	}
@@ -109,6 +256,14 @@ func (g *Game) Cleanup() error {
 		g.Text.Cleanup()
 	}
 
+	if g.Particles != nil {
+		g.Particles.Cleanup()
+	}
+
+	if g.Resources != nil {
+		g.Resources.Cleanup()
+	}
+
 	if g.soundsPlayer != nil {
 		if err := g.soundsPlayer.Cleanup(); err != nil {
 			return fmt.Errorf("failed to cleanup sounds player: %w", err)
@@ -119,32 +274,60 @@ func (g *Game) Cleanup() error {
 }
 
 func (g *Game) Init() error {
+	if err := g.loadManifest(); err != nil {
+		return err
+	}
+
+	// Missing files are reported together, the loaders replace them with
+	// fallbacks or silence where they can.
+	if err := g.manifest.Validate(g.Assets); err != nil {
+		log.Printf("warning: missing assets:\n%v", err)
+	}
+
+	g.Resources = resource.NewManager(g.Assets)
+
 	err := g.loadShaders()
 	if err != nil {
 		return fmt.Errorf("failed to load shaders: %w", err)
 	}
 
-	projection := mgl32.Ortho2D(0, float32(g.Width), float32(g.Height), 0)
-	resource.GetShader("sprite").SetInteger("image", 0, true)
-	resource.GetShader("sprite").SetMatrix4("projection", &projection, false)
-	resource.GetShader("particle").SetInteger("sprite", 0, true)
-	resource.GetShader("particle").SetMatrix4("projection", &projection, false)
+	spriteShader, err := g.Resources.Shader("sprite")
+	if err != nil {
+		return err
+	}
 
-	g.Renderer = render.NewSpriteRenderer(resource.GetShader("sprite"))
-	g.Effects, err = render.NewPostProcessor(resource.GetShader("postprocessing"), g.Width, g.Height)
+	particleShader, err := g.Resources.Shader("particle")
+	if err != nil {
+		return err
+	}
+
+	postShader, err := g.Resources.Shader("postprocessing")
+	if err != nil {
+		return err
+	}
+
+	g.initUniforms(spriteShader, particleShader)
+
+	g.Renderer = render.NewSpriteRenderer(spriteShader)
+	g.Effects, err = render.NewPostProcessor(postShader, g.Width, g.Height, g.Settings.Samples)
 	if err != nil {
 		return fmt.Errorf("failed to create post processor: %w", err)
 	}
 
-	g.Text, err = render.NewTextRenderer(g.Width, g.Height)
+	g.Text, err = render.NewTextRenderer(g.Resources, g.Width, g.Height)
 	if err != nil {
 		return fmt.Errorf("failed to create text renderer: %w", err)
 	}
 
-	for path, size := range fontFiles {
-		if err := g.Text.Load(path, size); err != nil {
-			return fmt.Errorf("failed to load font: %w", err)
-		}
+	g.ui = ui.NewContext(ui.DefaultTheme(), g.Text, g.Renderer, g.Resources.RegionOrFallback("block"))
+
+	font, ok := g.manifest.Fonts[uiFont]
+	if !ok {
+		return fmt.Errorf("the manifest has no %s font", uiFont)
+	}
+
+	if err := g.Text.Load(font.Path, font.Size, font.Fallback...); err != nil {
+		return fmt.Errorf("failed to load font: %w", err)
 	}
 
 	err = g.loadTextures()
@@ -152,121 +335,182 @@ func (g *Game) Init() error {
 		return fmt.Errorf("failed to load textures: %w", err)
 	}
 
-	err = g.loadLevels()
+	err = g.InitSimulation()
 	if err != nil {
-		return fmt.Errorf("failed to load levels: %w", err)
+		return fmt.Errorf("failed to init simulation: %w", err)
 	}
 
-	g.PowerUps = make([]PowerUp, 0)
-
-	g.Particles = NewParticleGenerator(
-		resource.GetShader("particle"),
-		resource.GetTexture("particle"),
-		particleAmount,
-	)
+	g.Particles = NewParticleSystem(particleShader, g.Resources, g.tuning.Particles)
+	if err := g.loadEmitters(); err != nil {
+		return fmt.Errorf("failed to load particle emitters: %w", err)
+	}
 
 	g.background = NewObject(
 		mgl32.Vec2{0, 0},
 		mgl32.Vec2{float32(g.Width), float32(g.Height)},
-		resource.GetTexture("background"),
+		g.Resources.RegionOrFallback("background"),
 		nil,
 		nil,
 	)
+	g.background.Layer = LayerBackground
 
-	g.player = NewObject(
-		mgl32.Vec2{float32(g.Width)/2 - playerSize.X()/2, float32(g.Height) - playerSize.Y()},
-		playerSize,
-		resource.GetTexture("paddle"),
-		nil,
-		nil,
-	)
+	g.soundsPlayer, err = sound.NewPlayer(g.Assets, g.manifest.Sounds, g.manifest.Music)
+	if err != nil {
+		return fmt.Errorf("failed to create sounds player: %w", err)
+	}
 
-	g.ball = NewBall(
-		g.player.Position.Add(mgl32.Vec2{playerSize.X()/2 - ballRadius, -ballRadius * 2}),
-		ballRadius,
-		initBallVelocity,
-		resource.GetTexture("face"),
-	)
+	g.ApplySettings(g.Settings)
+	g.OnEvent(g.playEventSound)
+	g.OnEvent(g.burstParticles)
+	g.soundsPlayer.PlayMusic("background")
 
-	g.soundsPlayer, err = sound.NewPlayer()
+	return nil
+}
+
+// InitSimulation loads everything gameplay needs without touching OpenGL or
+// audio, so it can also back a headless game.
+func (g *Game) InitSimulation() error {
+	if err := g.loadManifest(); err != nil {
+		return err
+	}
+
+	err := g.loadLevels()
 	if err != nil {
-		return fmt.Errorf("failed to create sounds player: %w", err)
+		return fmt.Errorf("failed to load levels: %w", err)
 	}
 
-	g.soundsPlayer.PlayBgMusic()
+	g.PowerUps = make([]PowerUp, 0)
 
-	g.lives = startingLives
+	g.players = []*Player{
+		NewPlayer(g.Resources.RegionOrFallback("paddle"), g.tuning.StartingLives),
+		NewPlayer(g.Resources.RegionOrFallback("paddle"), g.tuning.StartingLives),
+	}
+
+	g.ball = NewBall(
+		mgl32.Vec2{0, 0},
+		g.tuning.BallRadius,
+		g.tuning.BallVelocity,
+		g.Resources.RegionOrFallback("face"),
+	)
+
+	g.ApplyMode()
 
 	return nil
 }
 
 func (g *Game) ProcessInput(dt float64) {
-	if g.State == StateActive {
-		velocity := playerVelocity * float32(dt)
+	g.Controls.Update()
 
-		if g.Keys[glfw.KeyA] {
-			if g.player.Position.X() >= 0 {
-				g.player.Position[0] -= velocity
+	if g.Controls.Pressed(0, controls.ToggleMouse) {
+		g.Controls.Mouse.Enabled = !g.Controls.Mouse.Enabled
+	}
 
-				if g.ball.Stuck {
-					g.ball.Position[0] -= velocity
-				}
-			}
-		}
+	if g.Controls.Pressed(0, controls.CaptureCursor) {
+		g.Controls.SetCursorCaptured(!g.Controls.CursorCaptured())
+	}
 
-		if g.Keys[glfw.KeyD] {
-			if g.player.Position.X() <= float32(g.Width)-g.player.Size.X() {
-				g.player.Position[0] += velocity
+	if g.State == StateActive && g.paused {
+		g.updateScreen()
+		return
+	}
 
-				if g.ball.Stuck {
-					g.ball.Position[0] += velocity
-				}
-			}
-		}
+	if g.State == StateActive && g.match == nil && g.Controls.Pressed(0, controls.Pause) {
+		g.paused = true
+		return
+	}
 
-		if g.Keys[glfw.KeySpace] {
-			g.ball.Stuck = false
-		}
+	if g.State == StateDemo && g.Controls.AnyPressed() {
+		g.StopDemo()
+		return
+	}
+
+	if g.State == StateControls {
+		g.processRebind()
+		return
+	}
+
+	if g.State == StateSettings || g.State == StateLevelSelect {
+		g.updateScreen()
+		return
 	}
 
 	if g.State == StateMenu {
-		if g.Keys[glfw.KeyEnter] && !g.KeysProcessed[glfw.KeyEnter] {
-			g.State = StateActive
-			g.KeysProcessed[glfw.KeyEnter] = true
-		}
-		if g.Keys[glfw.KeyW] && !g.KeysProcessed[glfw.KeyW] {
-			g.Level = (g.Level + 1) % len(g.Levels)
-			g.KeysProcessed[glfw.KeyW] = true
-		}
-		if g.Keys[glfw.KeyS] && !g.KeysProcessed[glfw.KeyS] {
-			if g.Level > 0 {
-				g.Level--
-			} else {
-				g.Level = len(g.Levels) - 1
-			}
-			g.KeysProcessed[glfw.KeyS] = true
+		g.idleTime += dt
+		if g.Controls.AnyPressed() {
+			g.idleTime = 0
+		}
+
+		if g.idleTime >= attractDelay && g.match == nil {
+			g.StartDemo()
+			return
+		}
+
+		if g.Controls.Pressed(0, controls.CycleMode) {
+			g.Mode = (g.Mode + 1) % modeCount
+			g.ApplyMode()
 		}
+		if g.Controls.Pressed(0, controls.OpenControls) && g.match == nil {
+			g.openRebind()
+			return
+		}
+		if g.Controls.Pressed(0, controls.OpenSettings) && g.match == nil {
+			g.openSettings()
+			return
+		}
+
+		g.updateScreen()
 	}
 
-	if g.State == StateWin {
-		if g.Keys[glfw.KeyEnter] {
-			g.KeysProcessed[glfw.KeyEnter] = true
-			g.Effects.Chaos = false
+	if g.State == StateWin && g.match == nil {
+		if g.Controls.Pressed(0, controls.Confirm) {
+			g.chaos = false
 			g.State = StateMenu
 		}
 	}
 }
 
+// StartDemo lets the autopilot play the selected level as an attract mode.
+func (g *Game) StartDemo() {
+	g.Mode = ModeSingle
+	g.ApplyMode()
+	g.ResetLevel()
+	g.ResetScores()
+	g.bots[0] = NewAutopilot()
+	g.State = StateDemo
+}
+
+func (g *Game) StopDemo() {
+	g.bots[0] = nil
+	g.returnToMenu()
+}
+
+func (g *Game) playing() bool {
+	return g.State == StateActive || g.State == StateDemo
+}
+
 func (g *Game) Render() {
-	if g.State == StateActive || g.State == StateMenu || g.State == StateWin {
+	g.viewport.Apply()
+
+	if g.State == StateActive || g.State == StateMenu || g.State == StateLevelSelect || g.State == StateWin || g.State == StateDemo {
+		g.Effects.Confuse = g.confuse
+		g.Effects.Chaos = g.chaos
+		g.Effects.Shake = g.shaking
+
 		g.Effects.BeginRender()
 
 		{
+			g.Renderer.Begin()
 			g.background.Draw(g.Renderer)
 
 			g.Levels[g.Level].Draw(g.Renderer)
 
-			g.player.Draw(g.Renderer)
+			if g.Levels[g.Level].Boss != nil {
+				g.Levels[g.Level].Boss.Draw(g.Renderer)
+			}
+
+			for _, p := range g.activePlayers() {
+				p.Paddle.Draw(g.Renderer)
+			}
 
 			for i := range g.PowerUps {
 				if !g.PowerUps[i].Destroyed {
@@ -274,207 +518,618 @@ func (g *Game) Render() {
 				}
 			}
 
+			g.Renderer.End()
+
+			// The ball goes over the particles it leaves behind.
 			g.Particles.Draw()
+
+			g.Renderer.Begin()
 			g.ball.Draw(g.Renderer)
+			g.Renderer.End()
 		}
 
 		g.Effects.EndRender()
 		g.Effects.Render(glfw.GetTime())
 
-		g.Text.RenderText(fmt.Sprintf("Lives: %d", g.lives), 5, 5, 1, &mgl32.Vec3{1, 1, 1})
+		g.renderHUD()
+
+		if g.Levels[g.Level].Boss != nil {
+			g.Levels[g.Level].Boss.DrawHealthBar(g.Renderer, g.Width)
+		}
 	}
 
-	if g.State == StateMenu {
-		g.Text.RenderText("Press ENTER to start", 250, float32(g.Height)/2, 1, &mgl32.Vec3{1, 1, 1})
-		g.Text.RenderText("Press W or S to select level", 245, float32(g.Height)/2+20, 0.75, &mgl32.Vec3{1, 1, 1})
+	if g.State == StateDemo {
+		g.renderCentered("DEMO - press any key", float32(g.Height)/2, 1, &mgl32.Vec3{1, 1, 1})
+	}
+
+	if g.State == StateControls {
+		g.renderRebind()
+	}
+
+	g.drawScreen()
+
+	g.renderReloadError()
+
+	if g.match != nil {
+		g.drawMiniBoard(g.match.Opponent(), mgl32.Vec2{float32(g.Width) * (1 - miniBoardScale), 0}, miniBoardScale)
+
+		if status := g.match.Status(); status != "" {
+			g.Text.RenderText(status, 40, float32(g.Height)/2, 1, &mgl32.Vec3{1, 1, 0})
+		}
+
+		return
 	}
 
 	if g.State == StateWin {
-		g.Text.RenderText("You WON!!!", 320, float32(g.Height)/2-20, 1, &mgl32.Vec3{0, 1, 0})
-		g.Text.RenderText("Press ENTER to retry or ESC to quit", 130, float32(g.Height)/2, 1, &mgl32.Vec3{1, 1, 0})
+		g.renderCentered("You WON!!!", float32(g.Height)/2-20, 1, &mgl32.Vec3{0, 1, 0})
+		g.renderCentered("Press "+g.keyLabel(controls.Confirm)+" to retry or ESC to quit", float32(g.Height)/2, 1, &mgl32.Vec3{1, 1, 0})
+	}
+}
+
+// The HUD sits on top of the bricks, the shadow keeps it readable.
+var hudStyle = render.TextStyle{
+	Color:       mgl32.Vec3{1, 1, 1},
+	Shadow:      mgl32.Vec2{1.5, 1.5},
+	ShadowColor: mgl32.Vec4{0, 0, 0, 0.8},
+}
+
+// renderCentered draws text centered across the screen.
+func (g *Game) renderCentered(text string, y, scale float32, color *mgl32.Vec3) {
+	g.Text.RenderTextAligned(text, float32(g.Width)/2, y, scale, color, render.AlignCenter)
+}
+
+func (g *Game) renderHUD() {
+	if g.Mode == ModeSingle {
+		p := g.players[0]
+		g.Text.RenderTextStyled(fmt.Sprintf("Lives: %d  Score: %d", p.Lives, p.Score), 5, 5, 1, &hudStyle, render.AlignLeft)
+
+		return
+	}
+
+	for i, p := range g.activePlayers() {
+		x := 5 + float32(i*g.Width/2)
+		y := float32(5)
+		if p.Top {
+			y = float32(g.Height) - 25
+		}
+
+		g.Text.RenderTextStyled(fmt.Sprintf("P%d Lives: %d  Score: %d", i+1, p.Lives, p.Score), x, y, 0.75, &hudStyle, render.AlignLeft)
 	}
 }
 
 func (g *Game) Update(dt float64) {
+	if g.match != nil {
+		g.match.Update(dt)
+		return
+	}
+
+	if g.paused && g.State == StateActive {
+		return
+	}
+
+	g.accumulator += math.Min(dt, maxFrameTime)
+
+	for g.accumulator >= tickDuration {
+		if g.State == StateActive {
+			for i := range g.activePlayers() {
+				g.inputs[i] = g.ControlInput(i)
+			}
+		}
+
+		g.step(tickDuration)
+		g.accumulator -= tickDuration
+	}
+}
+
+// Tick advances the simulation by one fixed step using the given inputs
+// instead of the keyboard state.
+func (g *Game) Tick(inputs ...Input) {
+	copy(g.inputs[:], inputs)
+	g.step(tickDuration)
+}
+
+func (g *Game) step(dt float64) {
+	g.tick++
+
+	if g.playing() {
+		g.applyInputs(dt)
+	}
+
+	g.Levels[g.Level].Update()
 	g.ball.Move(dt, g.Width)
 	g.DoCollisions()
-	g.Particles.Update(dt, &g.ball.Object, 2, mgl32.Vec2{g.ball.Radius / 2, g.ball.Radius / 2})
+
+	if g.Particles != nil {
+		center := g.ball.Position.Add(mgl32.Vec2{g.ball.Radius, g.ball.Radius})
+		g.Particles.Emit("trail", dt, center, g.ball.Velocity)
+		g.Particles.Update(dt)
+	}
+
 	g.UpdatePowerUps(dt)
 
-	isLevelCompleted := g.Levels[g.Level].IsCompleted()
-	if g.State == StateActive && isLevelCompleted {
-		g.ResetLevel()
-		g.ResetPlayer()
-		g.Effects.Chaos = true
-		g.State = StateWin
+	if g.playing() {
+		g.UpdateBoss(dt)
 	}
 
-	if g.ball.Position.Y() >= float32(g.Height) {
-		g.lives -= 1
-		if g.lives == 0 {
+	isLevelCompleted := g.Levels[g.Level].IsCompleted()
+	if g.playing() && isLevelCompleted {
+		g.emit(Event{Type: EventLevelCompleted, Player: g.ball.Owner})
+
+		if g.match != nil {
+			g.match.finish(g, true)
+		} else if g.State == StateDemo {
+			g.StopDemo()
+		} else {
 			g.ResetLevel()
-			g.State = StateMenu
+			g.ResetPlayers(0)
+			g.chaos = true
+			g.State = StateWin
 		}
+	}
 
-		g.ResetPlayer()
+	center := g.ball.Position.X() + g.ball.Radius
+	if g.ball.Position.Y() >= float32(g.Height) {
+		g.LoseLife(g.playerAt(false, center))
+	} else if g.ball.OpenTop && g.ball.Position.Y()+g.ball.Size.Y() <= 0 {
+		g.LoseLife(g.playerAt(true, center))
 	}
 
 	if g.shakeTime > 0 {
 		g.shakeTime -= dt
 		if g.shakeTime <= 0 {
-			g.Effects.Shake = false
+			g.shaking = false
+		}
+	}
+
+	for _, listener := range g.onTick {
+		listener()
+	}
+}
+
+func (g *Game) applyInputs(dt float64) {
+	velocity := g.tuning.PlayerVelocity * float32(dt)
+
+	for i, p := range g.activePlayers() {
+		if g.bots[i] != nil {
+			g.inputs[i] = g.bots[i].Input(g, i)
+		}
+
+		var dx float32
+		speed := velocity * g.inputs[i].Speed()
+
+		if g.inputs[i]&InputLeft != 0 {
+			dx += p.Move(-speed)
+		}
+
+		if g.inputs[i]&InputRight != 0 {
+			dx += p.Move(speed)
 		}
+
+		if g.ball.Stuck && g.ball.Owner == i {
+			g.ball.Position[0] += dx
+
+			if g.inputs[i]&InputLaunch != 0 {
+				g.ball.Stuck = false
+			}
+		}
+	}
+}
+
+// ControlInput reads the movement and launch actions bound for a player,
+// falling back to their gamepad stick for proportional movement.
+func (g *Game) ControlInput(player int) Input {
+	var input Input
+
+	if g.Controls.Held(player, controls.MoveLeft) {
+		input |= InputLeft
+	}
+
+	if g.Controls.Held(player, controls.MoveRight) {
+		input |= InputRight
+	}
+
+	if g.Controls.Held(player, controls.Launch) {
+		input |= InputLaunch
+	}
+
+	if input&(InputLeft|InputRight) == 0 {
+		axis := g.Controls.Axis(player)
+		switch {
+		case axis < 0:
+			input = (input | InputLeft).WithSpeed(-axis)
+		case axis > 0:
+			input = (input | InputRight).WithSpeed(axis)
+		}
+	}
+
+	if input&(InputLeft|InputRight) == 0 && player == 0 {
+		input |= g.pointerInput(g.players[player])
+	}
+
+	return input
+}
+
+// pointerInput steers the paddle towards the cursor. The speed is recomputed
+// every tick from the remaining gap, which settles on the cursor without
+// overshooting it even with the versus input delay.
+func (g *Game) pointerInput(p *Player) Input {
+	x, _, ok := g.Controls.Pointer()
+	if !ok {
+		return 0
+	}
+
+	gap := x*float32(g.Width) - (p.Paddle.Position.X() + p.Paddle.Size.X()/2)
+	distance := float32(math.Abs(float64(gap)))
+	if distance < mouseDeadband {
+		return 0
+	}
+
+	response := math.Max(g.Controls.Mouse.Smoothing, mouseResponse)
+	speed := float32(math.Min(float64(distance)/response, float64(g.Controls.Mouse.MaxSpeed)))
+
+	if gap < 0 {
+		return InputLeft.WithSpeed(speed / g.tuning.PlayerVelocity)
 	}
+
+	return InputRight.WithSpeed(speed / g.tuning.PlayerVelocity)
 }
 
 func (g *Game) DoCollisions() {
-	for _, brick := range g.Levels[g.Level].Bricks {
+	level := &g.Levels[g.Level]
+
+	for _, brick := range level.Bricks {
 		if !brick.Destroyed {
-			r := CheckBallCollision(g.ball, brick)
+			r := CheckBallCollision(g.ball, &brick.Object)
+
+			if brick == g.ball.teleportGuard {
+				if !r.Collided {
+					g.ball.teleportGuard = nil
+				}
+
+				continue
+			}
+
 			if r.Collided {
+				if !brick.Blocks(g.ball.Velocity) {
+					continue
+				}
+
+				if brick.Kind == TeleporterBrick {
+					g.teleportBall(level.Partner(brick))
+					continue
+				}
+
+				if brick.Kind == SwitchBrick {
+					level.ToggleDoors(brick.Link)
+				}
+
 				if !brick.IsSolid {
 					brick.Destroyed = true
-					g.SpawnPowerUps(brick)
-					g.soundsPlayer.PlayNonSolidBlockBleep()
+					g.players[g.ball.Owner].Score += brickScore
+					g.SpawnPowerUps(&brick.Object)
+					g.emit(Event{
+						Type:     EventBrickDestroyed,
+						Player:   g.ball.Owner,
+						Position: brick.Position.Add(brick.Size.Mul(0.5)),
+						Color:    brick.Color,
+					})
 				} else {
-					g.shakeTime = 0.05
-					g.Effects.Shake = true
-					g.soundsPlayer.PlaySolidBlockBleep()
+					g.shake(0.05)
+					g.emit(Event{Type: EventSolidHit, Player: g.ball.Owner, Position: g.ball.Position})
 				}
 
 				if g.ball.PassThrough && !brick.IsSolid {
 					continue
 				}
 
-				if r.Dir == LeftDirection || r.Dir == RightDirection {
-					g.ball.Velocity[0] = -g.ball.Velocity[0]
-					penetration := g.ball.Radius - float32(math.Abs(float64(r.Diff.X())))
-
-					if r.Dir == LeftDirection {
-						g.ball.Position[0] += penetration
-					} else {
-						g.ball.Position[0] -= penetration
-					}
-				} else {
-					g.ball.Velocity[1] = -g.ball.Velocity[1]
-					penetration := g.ball.Radius - float32(math.Abs(float64(r.Diff.Y())))
-
-					if r.Dir == UpDirection {
-						g.ball.Position[1] -= penetration
-					} else {
-						g.ball.Position[1] += penetration
-					}
+				// A moving brick deflects the ball but keeps its speed, or
+				// every hit would make it faster.
+				speed := g.ball.Velocity.Len()
+				g.bounceBall(r)
+				if deflected := g.ball.Velocity.Add(brick.Velocity); deflected.Len() > 0 {
+					g.ball.Velocity = deflected.Normalize().Mul(speed)
 				}
 			}
 		}
 	}
 
-	r := CheckBallCollision(g.ball, g.player)
-	if !g.ball.Stuck && r.Collided {
-		centerBoard := g.player.Position.X() + g.player.Size.X()/2
-		distance := g.ball.Position.X() + g.ball.Radius - centerBoard
-		percentage := distance / (g.player.Size.X() / 2)
-
-		var strength float32 = 2
-		oldVelocity := g.ball.Velocity
-		g.ball.Velocity[0] = initBallVelocity.X() * percentage * strength
-		g.ball.Velocity[1] = -1 * float32(math.Abs(float64(g.ball.Velocity.Y())))
-		g.ball.Velocity = g.ball.Velocity.Normalize().Mul(oldVelocity.Len())
-		g.ball.Stuck = g.ball.Sticky
+	for i, p := range g.activePlayers() {
+		r := CheckBallCollision(g.ball, p.Paddle)
+		if !g.ball.Stuck && r.Collided {
+			centerBoard := p.Paddle.Position.X() + p.Paddle.Size.X()/2
+			distance := g.ball.Position.X() + g.ball.Radius - centerBoard
+			percentage := distance / (p.Paddle.Size.X() / 2)
+
+			oldVelocity := g.ball.Velocity
+			g.ball.Velocity[0] = g.tuning.BallVelocity.X() * percentage * deflectStrength
+			g.ball.Velocity[1] = -1 * float32(math.Abs(float64(g.ball.Velocity.Y())))
+			if p.Top {
+				g.ball.Velocity[1] = -g.ball.Velocity[1]
+			}
+			g.ball.Velocity = g.ball.Velocity.Normalize().Mul(oldVelocity.Len())
+			g.ball.Stuck = p.Sticky
+			g.ball.Owner = i
 
-		g.soundsPlayer.PlayPaddleBleep()
+			g.emit(Event{Type: EventPaddleHit, Player: i, Position: g.ball.Position})
+		}
 	}
 
 	for i := range g.PowerUps {
 		if !g.PowerUps[i].Destroyed {
-			if g.PowerUps[i].Position.Y() >= float32(g.Height) {
+			if g.PowerUps[i].Position.Y() >= float32(g.Height) || g.PowerUps[i].Position.Y()+g.PowerUps[i].Size.Y() <= 0 {
 				g.PowerUps[i].Destroyed = true
 			}
 
-			if CheckCollision(g.player, &g.PowerUps[i].Object) {
-				g.ActivatePowerUp(&g.PowerUps[i])
-				g.PowerUps[i].Destroyed = true
-				g.PowerUps[i].Activated = true
-				g.soundsPlayer.PlayPowerUp()
+			for j, p := range g.activePlayers() {
+				if !g.PowerUps[i].Destroyed && CheckCollision(p.Paddle, &g.PowerUps[i].Object) {
+					g.PowerUps[i].Player = j
+					g.ActivatePowerUp(&g.PowerUps[i])
+					g.PowerUps[i].Destroyed = true
+					g.PowerUps[i].Activated = true
+					g.emit(Event{
+						Type:     EventPowerUpCollected,
+						Player:   j,
+						Position: g.PowerUps[i].Position,
+						Color:    g.PowerUps[i].Color,
+						Name:     g.PowerUps[i].Type,
+					})
+				}
+			}
+		}
+	}
+}
+
+func (g *Game) UpdateBoss(dt float64) {
+	level := &g.Levels[g.Level]
+	boss := level.Boss
+	if boss == nil || boss.Destroyed {
+		return
+	}
+
+	boss.Move(dt)
+	target := g.players[0].Paddle
+	boss.Fire(dt, target.Position.Add(target.Size.Mul(0.5)))
+
+	if boss.ShouldSpawnMinion(dt) {
+		level.SpawnMinion(boss)
+	}
+
+	r := CheckBallCollision(g.ball, &boss.Object)
+	if r.Collided {
+		boss.Hit()
+		g.players[g.ball.Owner].Score += bossScore
+		g.shake(0.1)
+		g.emit(Event{Type: EventBossHit, Player: g.ball.Owner, Position: g.ball.Position})
+		g.bounceBall(r)
+	}
+
+	for i := range boss.Projectiles {
+		for j, p := range g.activePlayers() {
+			if !boss.Projectiles[i].Destroyed && CheckCollision(p.Paddle, &boss.Projectiles[i]) {
+				boss.Projectiles[i].Destroyed = true
+				g.shake(0.2)
+				g.LoseLife(j)
+
+				return
 			}
 		}
 	}
+
+	boss.RemoveProjectiles(g.Height)
+}
+
+func (g *Game) LoseLife(player int) {
+	g.players[player].Lives -= 1
+	g.emit(Event{Type: EventLifeLost, Player: player, Position: g.ball.Position})
+
+	if g.players[player].Lives == 0 {
+		if g.match != nil {
+			g.match.finish(g, false)
+			return
+		}
+
+		if g.State == StateDemo {
+			g.StopDemo()
+			return
+		}
+
+		g.ResetLevel()
+		g.ResetScores()
+		g.State = StateMenu
+	}
+
+	g.ResetPlayers(player)
+}
+
+func (g *Game) ApplyMode() {
+	width := float32(g.Width)
+	top, bottom := g.players[1], g.players[0]
+
+	bottom.MinX, bottom.MaxX = 0, width
+	top.MinX, top.MaxX = 0, width
+	top.Top = false
+
+	var levelOffset float32
+
+	switch g.Mode {
+	case ModeSideBySide:
+		bottom.MaxX = width / 2
+		top.MinX = width / 2
+	case ModeTopBottom:
+		top.Top = true
+		levelOffset = playerSize.Y() * 3
+	}
+
+	g.ball.OpenTop = top.Top
+
+	for i := range g.Levels {
+		g.Levels[i].SetOffset(levelOffset)
+	}
+
+	g.ResetPlayers(0)
+}
+
+func (g *Game) activePlayers() []*Player {
+	if g.Mode == ModeSingle {
+		return g.players[:1]
+	}
+
+	return g.players
+}
+
+func (g *Game) playerAt(top bool, x float32) int {
+	for i, p := range g.activePlayers() {
+		if p.Top == top && x >= p.MinX && x < p.MaxX {
+			return i
+		}
+	}
+
+	return 0
+}
+
+func (g *Game) shake(duration float64) {
+	g.shakeTime = duration
+	g.shaking = true
+}
+
+func (g *Game) teleportBall(target *Brick) {
+	if target == nil {
+		return
+	}
+
+	center := target.Position.Add(target.Size.Mul(0.5))
+	g.ball.Position = center.Sub(mgl32.Vec2{g.ball.Radius, g.ball.Radius})
+	g.ball.teleportGuard = target
+}
+
+func (g *Game) bounceBall(r Collision) {
+	if r.Dir == LeftDirection || r.Dir == RightDirection {
+		g.ball.Velocity[0] = -g.ball.Velocity[0]
+		penetration := g.ball.Radius - float32(math.Abs(float64(r.Diff.X())))
+
+		if r.Dir == LeftDirection {
+			g.ball.Position[0] += penetration
+		} else {
+			g.ball.Position[0] -= penetration
+		}
+	} else {
+		g.ball.Velocity[1] = -g.ball.Velocity[1]
+		penetration := g.ball.Radius - float32(math.Abs(float64(r.Diff.Y())))
+
+		if r.Dir == UpDirection {
+			g.ball.Position[1] -= penetration
+		} else {
+			g.ball.Position[1] += penetration
+		}
+	}
 }
 
 func (g *Game) ResetLevel() {
-	for _, brick := range g.Levels[g.Level].Bricks {
-		brick.Destroyed = false
+	g.Levels[g.Level].Reset()
+
+	if g.Particles != nil {
+		g.Particles.Clear()
 	}
 
-	g.lives = startingLives
+	for _, p := range g.players {
+		p.Lives = g.tuning.StartingLives
+	}
 }
 
-func (g *Game) ResetPlayer() {
-	g.player.Position = mgl32.Vec2{float32(g.Width)/2 - playerSize.X()/2, float32(g.Height) - playerSize.Y()}
-	g.ball.Reset(
-		g.player.Position.Add(mgl32.Vec2{playerSize.X()/2 - ballRadius, -ballRadius * 2}),
-		initBallVelocity,
-	)
+// ResetScores starts a new game. Clearing a level keeps the scores running.
+func (g *Game) ResetScores() {
+	for _, p := range g.players {
+		p.Score = 0
+	}
+}
+
+func (g *Game) ResetPlayers(server int) {
+	for _, p := range g.activePlayers() {
+		x := (p.MinX + p.MaxX - p.Paddle.Size.X()) / 2
+		y := float32(g.Height) - p.Paddle.Size.Y()
+		if p.Top {
+			y = 0
+		}
+
+		p.Paddle.Position = mgl32.Vec2{x, y}
+	}
+
+	velocity := g.tuning.BallVelocity
+	if g.players[server].Top {
+		velocity[1] = -velocity[1]
+	}
+
+	g.ball.Reset(g.players[server].ServePosition(g.ball.Radius), velocity)
+	g.ball.Owner = server
 }
 
 func (g *Game) SpawnPowerUps(block *Object) {
-	if shouldSpawn(75) {
+	first := len(g.PowerUps)
+	defer func() {
+		if g.players[g.ball.Owner].Top {
+			for i := first; i < len(g.PowerUps); i++ {
+				g.PowerUps[i].Velocity[1] = -g.PowerUps[i].Velocity[1]
+			}
+		}
+	}()
+
+	if g.shouldSpawn(75) {
 		g.PowerUps = append(g.PowerUps, NewPowerUp(
 			"speed",
 			mgl32.Vec3{0.5, 0.5, 1},
 			0,
 			block.Position,
-			resource.GetTexture("powerup_speed"),
+			g.Resources.RegionOrFallback("powerup_speed"),
 		))
 	}
 
-	if shouldSpawn(75) {
+	if g.shouldSpawn(75) {
 		g.PowerUps = append(g.PowerUps, NewPowerUp(
 			"sticky",
 			mgl32.Vec3{1, 0.5, 1},
 			20,
 			block.Position,
-			resource.GetTexture("powerup_sticky"),
+			g.Resources.RegionOrFallback("powerup_sticky"),
 		))
 	}
 
-	if shouldSpawn(75) {
+	if g.shouldSpawn(75) {
 		g.PowerUps = append(g.PowerUps, NewPowerUp(
 			"pass-through",
 			mgl32.Vec3{0.5, 1, 0.5},
 			10,
 			block.Position,
-			resource.GetTexture("powerup_passthrough"),
+			g.Resources.RegionOrFallback("powerup_passthrough"),
 		))
 	}
 
-	if shouldSpawn(75) {
+	if g.shouldSpawn(75) {
 		g.PowerUps = append(g.PowerUps, NewPowerUp(
 			"pad-size-increase",
 			mgl32.Vec3{1, 0.6, 0.4},
 			0,
 			block.Position,
-			resource.GetTexture("powerup_increase"),
+			g.Resources.RegionOrFallback("powerup_increase"),
 		))
 	}
 
-	if shouldSpawn(15) {
+	if g.shouldSpawn(15) {
 		g.PowerUps = append(g.PowerUps, NewPowerUp(
 			"confuse",
 			mgl32.Vec3{1, 0.3, 0.3},
 			15,
 			block.Position,
-			resource.GetTexture("powerup_confuse"),
+			g.Resources.RegionOrFallback("powerup_confuse"),
 		))
 	}
 
-	if shouldSpawn(15) {
+	if g.shouldSpawn(15) {
 		g.PowerUps = append(g.PowerUps, NewPowerUp(
 			"chaos",
 			mgl32.Vec3{0.9, 0.25, 0.25},
 			15,
 			block.Position,
-			resource.GetTexture("powerup_chaos"),
+			g.Resources.RegionOrFallback("powerup_chaos"),
 		))
 	}
 }
@@ -490,22 +1145,23 @@ func (g *Game) UpdatePowerUps(dt float64) {
 				g.PowerUps[i].Activated = false
 				switch g.PowerUps[i].Type {
 				case "sticky":
-					if !g.IsOtherPowerUpActive("sticky") {
-						g.ball.Sticky = false
-						g.player.Color = mgl32.Vec3{1, 1, 1}
+					p := g.players[g.PowerUps[i].Player]
+					if !g.IsOtherPowerUpActive("sticky", g.PowerUps[i].Player) {
+						p.Sticky = false
+						p.Paddle.Color = mgl32.Vec3{1, 1, 1}
 					}
 				case "pass-through":
-					if !g.IsOtherPowerUpActive("pass-through") {
+					if !g.IsOtherPowerUpActive("pass-through", anyPlayer) {
 						g.ball.PassThrough = false
-						g.player.Color = mgl32.Vec3{1, 1, 1}
+						g.ball.Color = mgl32.Vec3{1, 1, 1}
 					}
 				case "confuse":
-					if !g.IsOtherPowerUpActive("confuse") {
-						g.Effects.Confuse = false
+					if !g.IsOtherPowerUpActive("confuse", anyPlayer) {
+						g.confuse = false
 					}
 				case "chaos":
-					if !g.IsOtherPowerUpActive("chaos") {
-						g.Effects.Chaos = false
+					if !g.IsOtherPowerUpActive("chaos", anyPlayer) {
+						g.chaos = false
 					}
 				}
 			}
@@ -524,31 +1180,38 @@ func (g *Game) UpdatePowerUps(dt float64) {
 }
 
 func (g *Game) ActivatePowerUp(powerUp *PowerUp) {
+	p := g.players[powerUp.Player]
+
 	switch powerUp.Type {
 	case "speed":
 		g.ball.Velocity = g.ball.Velocity.Mul(1.2)
 	case "sticky":
-		g.ball.Sticky = true
-		g.player.Color = mgl32.Vec3{1, 0.5, 1}
+		p.Sticky = true
+		p.Paddle.Color = mgl32.Vec3{1, 0.5, 1}
 	case "pass-through":
 		g.ball.PassThrough = true
 		g.ball.Color = mgl32.Vec3{1, 0.5, 0.5}
 	case "pad-size-increase":
-		g.player.Size[0] += 50
+		// The paddle never grows past its lane, or Move could not keep it in.
+		p.Paddle.Size[0] += 50
+		if lane := p.MaxX - p.MinX; p.Paddle.Size[0] > lane {
+			p.Paddle.Size[0] = lane
+		}
+		p.Move(0)
 	case "confuse":
-		if !g.Effects.Chaos {
-			g.Effects.Confuse = true
+		if !g.chaos {
+			g.confuse = true
 		}
 	case "chaos":
-		if !g.Effects.Confuse {
-			g.Effects.Chaos = true
+		if !g.confuse {
+			g.chaos = true
 		}
 	}
 }
 
-func (g *Game) IsOtherPowerUpActive(t string) bool {
+func (g *Game) IsOtherPowerUpActive(t string, player int) bool {
 	for i := range g.PowerUps {
-		if g.PowerUps[i].Activated && g.PowerUps[i].Type == t {
+		if g.PowerUps[i].Activated && g.PowerUps[i].Type == t && (player == anyPlayer || g.PowerUps[i].Player == player) {
 			return true
 		}
 	}
@@ -556,9 +1219,33 @@ func (g *Game) IsOtherPowerUpActive(t string) bool {
 	return false
 }
 
+func (g *Game) initUniforms(spriteShader, particleShader *shader.Shader) {
+	projection := mgl32.Ortho2D(0, float32(g.Width), float32(g.Height), 0)
+	spriteShader.SetInteger("image", 0, true)
+	spriteShader.SetMatrix4("projection", &projection, false)
+	particleShader.SetInteger("sprite", 0, true)
+	particleShader.SetMatrix4("projection", &projection, false)
+}
+
+// loadManifest reads the manifest once, Init and InitSimulation both need it.
+func (g *Game) loadManifest() error {
+	if g.manifest != nil {
+		return nil
+	}
+
+	m, err := assets.LoadManifest(g.Assets, ManifestFile)
+	if err != nil {
+		return fmt.Errorf("failed to load manifest: %w", err)
+	}
+
+	g.manifest = m
+
+	return nil
+}
+
 func (g *Game) loadShaders() error {
-	for name, sFile := range shaderFiles {
-		err := resource.LoadShader(sFile.v, sFile.f, sFile.g, name)
+	for name, s := range g.manifest.Shaders {
+		_, err := g.Resources.LoadShader(name, s.Vertex, s.Fragment, s.Geometry)
 		if err != nil {
 			return fmt.Errorf("failed to load %s shader: %w", name, err)
 		}
@@ -568,27 +1255,71 @@ func (g *Game) loadShaders() error {
 }
 
 func (g *Game) loadTextures() (err error) {
-	for name, tFile := range textureFiles {
-		err = resource.LoadTexture(tFile.path, tFile.alpha, name)
+	var packed []resource.AtlasImage
+
+	for name, t := range g.manifest.Textures {
+		options := resource.TextureOptions{Alpha: t.Alpha, Nearest: t.Nearest()}
+		if t.Atlas {
+			packed = append(packed, resource.AtlasImage{Name: name, FileName: t.Path, Options: options})
+			continue
+		}
+
+		_, err = g.Resources.LoadTexture(name, t.Path, options)
 		if err != nil {
 			return fmt.Errorf("failed to load %s texture: %w", name, err)
 		}
 	}
 
+	_, err = g.Resources.LoadAtlas(packed)
+	if err != nil {
+		return fmt.Errorf("failed to load atlas: %w", err)
+	}
+
+	return nil
+}
+
+func (g *Game) loadEmitters() error {
+	for name, path := range g.manifest.Particles {
+		def, err := LoadEmitterDef(g.Assets, path)
+		if err != nil {
+			return fmt.Errorf("failed to load %s emitter: %w", name, err)
+		}
+
+		g.Particles.SetEmitter(name, def)
+	}
+
 	return nil
 }
 
 func (g *Game) loadLevels() (err error) {
+	levelFiles := g.manifest.Levels
 	g.Levels = make([]Level, 0, len(levelFiles))
 
+	bosses := make(map[string]*BossDef, len(g.manifest.Bosses))
+	for name, path := range g.manifest.Bosses {
+		bosses[name], err = LoadBossDef(g.Assets, path)
+		if err != nil {
+			return fmt.Errorf("failed to load %s boss: %w", name, err)
+		}
+	}
+
 	for i := range levelFiles {
 		var l Level
 
-		err = l.Load(levelFiles[i], g.Width, g.Height/2)
+		err = l.Load(g.Assets, levelFiles[i], g.Width, g.Height/2, g.Resources)
 		if err != nil {
 			return fmt.Errorf("failed to load level %s: %w", levelFiles[i], err)
 		}
 
+		if l.BossName != "" {
+			def, ok := bosses[l.BossName]
+			if !ok {
+				return fmt.Errorf("unknown boss %s in level %s", l.BossName, levelFiles[i])
+			}
+
+			l.Boss = NewBoss(def, g.Resources)
+		}
+
 		g.Levels = append(g.Levels, l)
 	}
 
@@ -597,7 +1328,7 @@ func (g *Game) loadLevels() (err error) {
 	return nil
 }
 
-func shouldSpawn(chance int) bool {
-	r := rand.Int() % chance
+func (g *Game) shouldSpawn(chance int) bool {
+	r := g.rng.Int() % chance
 	return r == 0
 }
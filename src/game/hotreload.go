@@ -0,0 +1,217 @@
+package game
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-gl/mathgl/mgl32"
+
+	"breakout/src/resource"
+)
+
+const hotReloadInterval = 0.25
+
+var hotReloadDirs = []string{"shaders", "textures", "levels", "particles"}
+
+// hotReload watches the resource directories while the game runs. The last
+// failed reload stays on screen until the next reload succeeds.
+type hotReload struct {
+	root    string
+	watcher *resource.Watcher
+	elapsed float64
+	err     error
+}
+
+// EnableHotReload reloads shaders, textures, levels and particle emitters
+// when their files below root change. Root has to be a directory that Assets
+// reads loose files from. It needs the resources, so it is called after Init.
+func (g *Game) EnableHotReload(root string) error {
+	dirs := make([]string, 0, len(hotReloadDirs))
+	for _, dir := range hotReloadDirs {
+		dirs = append(dirs, filepath.Join(root, dir))
+	}
+
+	watcher, err := resource.NewWatcher(dirs...)
+	if err != nil {
+		return fmt.Errorf("failed to watch resources: %w", err)
+	}
+
+	g.hotReload = &hotReload{root: root, watcher: watcher}
+
+	return nil
+}
+
+// PollHotReload checks for changed files every hotReloadInterval seconds of
+// dt. It does nothing unless hot reload is enabled.
+func (g *Game) PollHotReload(dt float64) {
+	h := g.hotReload
+	if h == nil {
+		return
+	}
+
+	h.elapsed += dt
+	if h.elapsed < hotReloadInterval {
+		return
+	}
+	h.elapsed = 0
+
+	changed, err := h.watcher.Poll()
+	if err != nil {
+		log.Println("failed to poll resources:", err)
+		return
+	}
+
+	for _, path := range changed {
+		rel, err := filepath.Rel(h.root, path)
+		if err != nil {
+			log.Println("failed to resolve changed file:", err)
+			continue
+		}
+
+		reloaded, err := g.reloadFile(filepath.ToSlash(rel))
+		if err != nil {
+			log.Println(err)
+			h.err = err
+		} else if reloaded {
+			h.err = nil
+		}
+	}
+}
+
+// reloadFile reloads whatever was loaded from the file, a path in Assets, and
+// reports whether there was anything.
+func (g *Game) reloadFile(path string) (bool, error) {
+	if index := g.levelIndex(path); index >= 0 {
+		if err := g.reloadLevel(index); err != nil {
+			return false, fmt.Errorf("failed to reload level %s: %w", path, err)
+		}
+
+		log.Println("reloaded level", path)
+
+		return true, nil
+	}
+
+	if name := g.emitterName(path); name != "" {
+		def, err := LoadEmitterDef(g.Assets, path)
+		if err != nil {
+			return false, fmt.Errorf("failed to reload %s emitter: %w", name, err)
+		}
+
+		g.Particles.SetEmitter(name, def)
+		log.Println("reloaded emitter", name)
+
+		return true, nil
+	}
+
+	names, err := g.Resources.Reload(path)
+	if len(names) > 0 {
+		if initErr := g.reinitShaders(); initErr != nil {
+			err = errors.Join(err, fmt.Errorf("failed to set up reloaded shaders: %w", initErr))
+		}
+
+		log.Println("reloaded", strings.Join(names, ", "), "from", path)
+	}
+
+	return len(names) > 0, err
+}
+
+// reinitShaders sets the uniforms again, a reloaded shader is a new program
+// that starts with all of them at zero.
+func (g *Game) reinitShaders() error {
+	spriteShader, err := g.Resources.Shader("sprite")
+	if err != nil {
+		return err
+	}
+
+	particleShader, err := g.Resources.Shader("particle")
+	if err != nil {
+		return err
+	}
+
+	g.initUniforms(spriteShader, particleShader)
+	g.Effects.InitUniforms()
+	g.Text.InitUniforms()
+
+	return nil
+}
+
+// reloadLevel loads a level file again. The current level keeps the broken
+// bricks, cleared rows and boss, so the game goes on where it was.
+func (g *Game) reloadLevel(index int) error {
+	if g.match != nil {
+		return fmt.Errorf("levels cannot change during a versus match")
+	}
+
+	old := &g.Levels[index]
+
+	var l Level
+	if err := l.Load(g.Assets, g.manifest.Levels[index], g.Width, g.Height/2, g.Resources); err != nil {
+		return err
+	}
+
+	switch {
+	case l.BossName == old.BossName:
+		l.Boss = old.Boss
+	case l.BossName != "":
+		path, ok := g.manifest.Bosses[l.BossName]
+		if !ok {
+			return fmt.Errorf("unknown boss %s", l.BossName)
+		}
+
+		def, err := LoadBossDef(g.Assets, path)
+		if err != nil {
+			return fmt.Errorf("failed to load %s boss: %w", l.BossName, err)
+		}
+
+		l.Boss = NewBoss(def, g.Resources)
+	}
+
+	if index == g.Level {
+		l.KeepProgress(old)
+	} else {
+		l.SetOffset(old.offset)
+	}
+
+	// The ball may remember a teleporter of the old level.
+	if g.ball != nil {
+		g.ball.teleportGuard = nil
+	}
+
+	g.Levels[index] = l
+
+	return nil
+}
+
+func (g *Game) levelIndex(path string) int {
+	for i, file := range g.manifest.Levels {
+		if file == path {
+			return i
+		}
+	}
+
+	return -1
+}
+
+func (g *Game) emitterName(path string) string {
+	for name, file := range g.manifest.Particles {
+		if file == path {
+			return name
+		}
+	}
+
+	return ""
+}
+
+func (g *Game) renderReloadError() {
+	if g.hotReload == nil || g.hotReload.err == nil {
+		return
+	}
+
+	red := mgl32.Vec3{1, 0.2, 0.2}
+	for i, line := range strings.Split(g.hotReload.err.Error(), "\n") {
+		g.Text.RenderText(line, 5, 30+float32(i)*16, 0.5, &red)
+	}
+}
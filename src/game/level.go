@@ -3,7 +3,8 @@ package game
 import (
 	"bufio"
 	"fmt"
-	"os"
+	"io/fs"
+	"math/rand"
 	"strconv"
 	"strings"
 
@@ -14,44 +15,321 @@ import (
 )
 
 type Level struct {
-	Bricks []*Object
+	Bricks   []*Brick
+	BossName string
+	Boss     *Boss
+
+	width   float32
+	offset  float32
+	tick    uint64
+	unit    mgl32.Vec2
+	columns int
+	rows    int
+	cleared map[int]bool
+	// pruned counts the removals of destroyed spawned bricks, which shift
+	// the brick indices without always changing their number.
+	pruned int
+
+	resources *resource.Manager
+}
+
+type tile struct {
+	code int
+	kind BrickKind
+	link int
 }
 
-func (g *Level) Load(fileName string, levelWidth, levelHeight int) error {
-	g.Bricks = make([]*Object, 0)
+// Load reads a level file from fsys. The textures come from resources, which
+// is nil for headless games.
+func (g *Level) Load(fsys fs.FS, fileName string, levelWidth, levelHeight int, resources *resource.Manager) error {
+	g.Bricks = make([]*Brick, 0)
+	g.resources = resources
 
-	file, err := os.Open(fileName)
+	file, err := fsys.Open(fileName)
 	if err != nil {
 		return fmt.Errorf("failed to open level file: %w", err)
 	}
 	defer file.Close()
 
-	tileData := make([][]int, 0)
+	tileData := make([][]tile, 0)
+	rules := make([]motionRule, 0)
 
 	scanner := bufio.NewScanner(file)
 	for scanner.Scan() {
-		tileCodes := strings.Split(strings.TrimSpace(scanner.Text()), " ")
-		row := make([]int, len(tileCodes))
+		if strings.HasPrefix(scanner.Text(), "@boss") {
+			fields := strings.Fields(scanner.Text())
+			if len(fields) != 2 {
+				return fmt.Errorf("malformed boss directive %q", scanner.Text())
+			}
+
+			g.BossName = fields[1]
+
+			continue
+		}
+
+		if strings.HasPrefix(scanner.Text(), "@") {
+			rule, err := parseMotionRule(scanner.Text())
+			if err != nil {
+				return fmt.Errorf("failed to parse motion: %w", err)
+			}
+
+			rules = append(rules, rule)
+
+			continue
+		}
+
+		tileCodes := strings.Fields(scanner.Text())
+		row := make([]tile, len(tileCodes))
 
 		for i := range tileCodes {
-			tileCode, err := strconv.Atoi(tileCodes[i])
+			t, err := parseTile(tileCodes[i])
 			if err != nil {
 				return fmt.Errorf("failed to parse tile code: %w", err)
 			}
 
-			row[i] = tileCode
+			row[i] = t
 		}
 
 		tileData = append(tileData, row)
 	}
 
 	if len(tileData) > 0 {
-		g.init(tileData, levelWidth, levelHeight)
+		g.init(tileData, rules, levelWidth, levelHeight)
+	}
+
+	if err := g.validateLinks(); err != nil {
+		return fmt.Errorf("failed to validate links: %w", err)
+	}
+
+	return nil
+}
+
+func (g *Level) Update() {
+	g.tick++
+	g.moveBricks()
+}
+
+func (g *Level) Reset() {
+	moveIndex := 0
+	for _, brick := range g.Bricks {
+		if !brick.Spawned {
+			brick.Destroyed = false
+			g.Bricks[moveIndex] = brick
+			moveIndex++
+		}
+	}
+
+	g.Bricks = g.Bricks[:moveIndex]
+	g.cleared = make(map[int]bool)
+
+	if g.Boss != nil {
+		g.Boss.Reset()
+	}
+
+	g.tick = 0
+	g.moveBricks()
+}
+
+// KeepProgress carries the state of a level over to this one, reloaded
+// from its file. Bricks are matched by grid position and kind, so edited
+// bricks start intact while the others stay broken.
+func (g *Level) KeepProgress(old *Level) {
+	type key struct {
+		origin mgl32.Vec2
+		kind   BrickKind
+	}
+
+	destroyed := make(map[key]bool, len(old.Bricks))
+	for _, brick := range old.Bricks {
+		if !brick.Spawned {
+			destroyed[key{brick.Origin, brick.Kind}] = brick.Destroyed
+		}
+	}
+
+	for _, brick := range g.Bricks {
+		brick.Destroyed = destroyed[key{brick.Origin, brick.Kind}]
+	}
+
+	for _, brick := range old.Bricks {
+		if brick.Spawned && !brick.Destroyed {
+			g.Bricks = append(g.Bricks, brick)
+		}
+	}
+
+	if g.cleared == nil {
+		g.cleared = make(map[int]bool)
+	}
+
+	for row, cleared := range old.cleared {
+		g.cleared[row] = cleared
+	}
+
+	g.tick = old.tick
+	g.offset = old.offset
+	g.moveBricks()
+}
+
+func (g *Level) SetOffset(offset float32) {
+	g.offset = offset
+	g.moveBricks()
+}
+
+func (g *Level) moveBricks() {
+	t := float64(g.tick) * tickDuration
+
+	for _, brick := range g.Bricks {
+		if brick.Spawned {
+			continue
+		}
+
+		brick.Position = brick.Origin.Add(mgl32.Vec2{0, g.offset})
+
+		if brick.Motion != nil {
+			offset, velocity := brick.Motion.Offset(t, brick.Origin.X(), brick.Size.X(), g.width)
+			brick.Position = brick.Position.Add(offset)
+			brick.Velocity = velocity
+		}
+	}
+}
+
+func (g *Level) SpawnMinion(boss *Boss) {
+	g.pruneSpawned()
+
+	alive := 0
+	for _, brick := range g.Bricks {
+		if brick.Spawned && !brick.Destroyed {
+			alive++
+		}
+	}
+
+	if alive >= boss.Def.Minion.Max {
+		return
+	}
+
+	size := mgl32.Vec2{boss.Size.X() / 3, boss.Size.Y() / 3}
+	color := tileColor(tile{code: boss.Def.Minion.Code})
+	brick := NewBrick(NormalBrick, 0, NewObject(
+		boss.Position.Add(mgl32.Vec2{boss.Size.X()/2 - size.X()/2, boss.Size.Y()}),
+		size,
+		g.resources.RegionOrFallback("block"),
+		&color,
+		nil,
+	))
+	brick.Spawned = true
+
+	g.Bricks = append(g.Bricks, brick)
+}
+
+// pruneSpawned drops the destroyed minions and garbage so a long fight does
+// not grow the brick list.
+func (g *Level) pruneSpawned() {
+	kept := g.Bricks[:0]
+	for _, brick := range g.Bricks {
+		if !brick.Spawned || !brick.Destroyed {
+			kept = append(kept, brick)
+		}
+	}
+
+	if len(kept) == len(g.Bricks) {
+		return
+	}
+
+	for i := len(kept); i < len(g.Bricks); i++ {
+		g.Bricks[i] = nil
+	}
+
+	g.Bricks = kept
+	g.pruned++
+}
+
+// ClearedRows returns how many rows had their last breakable brick
+// destroyed since the previous call.
+func (g *Level) ClearedRows() int {
+	remaining := make(map[int]int)
+	for _, brick := range g.Bricks {
+		if brick.Spawned || brick.IsSolid {
+			continue
+		}
+
+		if _, ok := remaining[brick.Row]; !ok {
+			remaining[brick.Row] = 0
+		}
+
+		if !brick.Destroyed {
+			remaining[brick.Row]++
+		}
+	}
+
+	count := 0
+	for row, left := range remaining {
+		if left == 0 && !g.cleared[row] {
+			g.cleared[row] = true
+			count++
+		}
+	}
+
+	return count
+}
+
+func (g *Level) AddGarbage(count int, rng *rand.Rand) {
+	if g.columns == 0 {
+		return
+	}
+
+	color := mgl32.Vec3{0.5, 0.5, 0.5}
+
+	for attempts := 0; count > 0 && attempts < count*g.columns*g.rows; attempts++ {
+		cell := mgl32.Vec2{
+			float32(rng.Intn(g.columns)) * g.unit.X(),
+			float32(rng.Intn(g.rows))*g.unit.Y() + g.offset,
+		}
+		garbage := NewObject(cell, g.unit, g.resources.RegionOrFallback("block"), &color, nil)
+
+		if g.occupied(garbage) {
+			continue
+		}
+
+		brick := NewBrick(NormalBrick, 0, garbage)
+		brick.Spawned = true
+		brick.Row = -1
+
+		g.Bricks = append(g.Bricks, brick)
+		count--
+	}
+}
+
+func (g *Level) occupied(o *Object) bool {
+	shrunk := *o
+	shrunk.Position = o.Position.Add(mgl32.Vec2{1, 1})
+	shrunk.Size = o.Size.Sub(mgl32.Vec2{2, 2})
+
+	for _, brick := range g.Bricks {
+		if !brick.Destroyed && CheckCollision(&brick.Object, &shrunk) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (g *Level) Partner(brick *Brick) *Brick {
+	for _, b := range g.Bricks {
+		if b != brick && b.Kind == brick.Kind && b.Link == brick.Link {
+			return b
+		}
 	}
 
 	return nil
 }
 
+func (g *Level) ToggleDoors(link int) {
+	for _, b := range g.Bricks {
+		if b.Kind == DoorBrick && b.Link == link {
+			b.Destroyed = !b.Destroyed
+		}
+	}
+}
+
 func (g *Level) Draw(renderer *render.SpriteRenderer) {
 	for _, brick := range g.Bricks {
 		if !brick.Destroyed {
@@ -61,6 +339,10 @@ func (g *Level) Draw(renderer *render.SpriteRenderer) {
 }
 
 func (g *Level) IsCompleted() bool {
+	if g.Boss != nil {
+		return g.Boss.Destroyed
+	}
+
 	for _, brick := range g.Bricks {
 		if !brick.IsSolid && !brick.Destroyed {
 			return false
@@ -70,48 +352,149 @@ func (g *Level) IsCompleted() bool {
 	return true
 }
 
-func (g *Level) init(tileData [][]int, levelWidth, levelHeight int) {
+func (g *Level) init(tileData [][]tile, rules []motionRule, levelWidth, levelHeight int) {
+	g.width = float32(levelWidth)
+
 	height := len(tileData)
 	width := len(tileData[0])
 	unitWidth := float32(levelWidth) / float32(width)
 	unitHeight := float32(levelHeight) / float32(height)
 
+	g.unit = mgl32.Vec2{unitWidth, unitHeight}
+	g.columns, g.rows = width, height
+	g.cleared = make(map[int]bool)
+
 	for y := 0; y < height; y++ {
-		for x := 0; x < width; x++ {
-			if tileData[y][x] == 1 {
-				brickObj := NewObject(
-					mgl32.Vec2{unitWidth * float32(x), unitHeight * float32(y)},
-					mgl32.Vec2{unitWidth, unitHeight},
-					resource.GetTexture("block_solid"),
-					&mgl32.Vec3{0.8, 0.8, 0.7},
-					nil,
-				)
-				brickObj.IsSolid = true
-
-				g.Bricks = append(g.Bricks, brickObj)
-			} else if tileData[y][x] > 1 {
-				color := mgl32.Vec3{1, 1, 1}
-				switch tileData[y][x] {
-				case 2:
-					color = mgl32.Vec3{0.2, 0.6, 1}
-				case 3:
-					color = mgl32.Vec3{0, 0.7, 0}
-				case 4:
-					color = mgl32.Vec3{0.8, 0.8, 0.4}
-				case 5:
-					color = mgl32.Vec3{1, 0.5, 0}
-				}
+		for x := 0; x < len(tileData[y]); x++ {
+			t := tileData[y][x]
+			if t.kind == NormalBrick && t.code == 0 {
+				continue
+			}
+
+			textureName := "block_solid"
+			if t.kind == NormalBrick || t.kind == OneWayBrick {
+				textureName = "block"
+			}
+
+			color := tileColor(t)
+			brickObj := NewObject(
+				mgl32.Vec2{unitWidth * float32(x), unitHeight * float32(y)},
+				mgl32.Vec2{unitWidth, unitHeight},
+				g.resources.RegionOrFallback(textureName),
+				&color,
+				nil,
+			)
 
-				brickObj := NewObject(
-					mgl32.Vec2{unitWidth * float32(x), unitHeight * float32(y)},
-					mgl32.Vec2{unitWidth, unitHeight},
-					resource.GetTexture("block"),
-					&color,
-					nil,
-				)
+			brick := NewBrick(t.kind, t.link, brickObj)
+			brick.Row = y
 
-				g.Bricks = append(g.Bricks, brickObj)
+			for i := range rules {
+				if rules[i].matches(y, x) {
+					motion := rules[i].motion
+					brick.Motion = &motion
+				}
 			}
+
+			g.Bricks = append(g.Bricks, brick)
+		}
+	}
+}
+
+func (g *Level) validateLinks() error {
+	teleporters := make(map[int]int)
+	switches := make(map[int]bool)
+	doors := make(map[int]bool)
+
+	for _, b := range g.Bricks {
+		switch b.Kind {
+		case TeleporterBrick:
+			teleporters[b.Link]++
+		case SwitchBrick:
+			switches[b.Link] = true
+		case DoorBrick:
+			doors[b.Link] = true
+		}
+	}
+
+	for link, count := range teleporters {
+		if count != 2 {
+			return fmt.Errorf("teleporter %d has %d tiles, expected 2", link, count)
 		}
 	}
+
+	for link := range switches {
+		if !doors[link] {
+			return fmt.Errorf("switch %d has no doors", link)
+		}
+	}
+
+	return nil
+}
+
+// Tile codes are either a plain number (0 is empty, 1 is solid, 2-5 are
+// colored bricks) or a letter followed by a number: T<link> teleporter,
+// S<link> switch, D<link> door and O<color> one-way brick.
+func parseTile(code string) (tile, error) {
+	var t tile
+
+	kinds := map[byte]BrickKind{
+		'T': TeleporterBrick,
+		'S': SwitchBrick,
+		'D': DoorBrick,
+		'O': OneWayBrick,
+	}
+
+	kind, ok := kinds[code[0]]
+	if ok {
+		code = code[1:]
+	}
+
+	value, err := strconv.Atoi(code)
+	if err != nil {
+		return t, err
+	}
+
+	if value < 0 {
+		return t, fmt.Errorf("negative tile value %d", value)
+	}
+
+	switch {
+	case !ok && value == 1:
+		t.kind = SolidBrick
+		t.code = value
+	case !ok, kind == OneWayBrick:
+		t.kind = kind
+		t.code = value
+	default:
+		t.kind = kind
+		t.link = value
+	}
+
+	return t, nil
+}
+
+func tileColor(t tile) mgl32.Vec3 {
+	switch t.kind {
+	case SolidBrick:
+		return mgl32.Vec3{0.8, 0.8, 0.7}
+	case TeleporterBrick:
+		return mgl32.Vec3{0.6, 0.3, 1}
+	case SwitchBrick:
+		return mgl32.Vec3{1, 0.9, 0.2}
+	case DoorBrick:
+		return mgl32.Vec3{0.4, 0.5, 0.6}
+	}
+
+	switch t.code {
+	case 2:
+		return mgl32.Vec3{0.2, 0.6, 1}
+	case 3:
+		return mgl32.Vec3{0, 0.7, 0}
+	case 4:
+		return mgl32.Vec3{0.8, 0.8, 0.4}
+	case 5:
+		return mgl32.Vec3{1, 0.5, 0}
+	}
+
+	return mgl32.Vec3{1, 1, 1}
 }
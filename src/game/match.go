@@ -0,0 +1,330 @@
+package game
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math"
+
+	"github.com/go-gl/mathgl/mgl32"
+
+	"breakout/src/netplay"
+	"breakout/src/settings"
+)
+
+const (
+	hashInterval   = 60
+	noWinner       = -1
+	miniBoardScale = 0.25
+)
+
+var ErrDesync = errors.New("simulation desync")
+
+// Match runs a lockstep head-to-head game. Both peers simulate both boards,
+// so garbage rows and results stay identical without sending game state.
+type Match struct {
+	conn   *netplay.Conn
+	boards [2]*Game
+	local  int
+	delay  uint64
+
+	// input reads the local player, the controls unless replaced.
+	input func() Input
+
+	tick         uint64
+	scheduled    uint64
+	inputs       [2]map[uint64]Input
+	hashes       map[uint64]uint64
+	remoteHashes map[uint64]uint64
+	accumulator  float64
+
+	Winner int
+	Err    error
+}
+
+func NewMatch(local *Game, conn *netplay.Conn, player int, config netplay.Config) (*Match, error) {
+	if int(config.Level) >= len(local.Levels) {
+		return nil, fmt.Errorf("unknown level %d", config.Level)
+	}
+
+	remote := NewGame(local.Width, local.Height)
+	remote.Assets = local.Assets
+	if err := remote.InitSimulation(); err != nil {
+		return nil, fmt.Errorf("failed to init opponent board: %w", err)
+	}
+
+	m := &Match{
+		conn:         conn,
+		local:        player,
+		delay:        uint64(config.Delay),
+		scheduled:    uint64(config.Delay),
+		inputs:       [2]map[uint64]Input{make(map[uint64]Input), make(map[uint64]Input)},
+		hashes:       make(map[uint64]uint64),
+		remoteHashes: make(map[uint64]uint64),
+		Winner:       noWinner,
+	}
+
+	m.boards[player] = local
+	m.boards[1-player] = remote
+	m.input = func() Input { return local.ControlInput(0) }
+
+	for _, b := range m.boards {
+		b.match = m
+		b.setTuning(settings.DefaultGameplay())
+		b.Mode = ModeSingle
+		b.Level = int(config.Level)
+		b.Seed(config.Seed)
+		b.ApplyMode()
+		b.ResetLevel()
+		b.ResetScores()
+		b.State = StateActive
+	}
+
+	return m, nil
+}
+
+func (m *Match) Over() bool {
+	return m.Winner != noWinner || m.Err != nil
+}
+
+func (m *Match) Update(dt float64) {
+	if m.Over() {
+		return
+	}
+
+	m.receive()
+
+	m.accumulator += math.Min(dt, maxFrameTime)
+
+	for m.accumulator >= tickDuration && !m.Over() {
+		if m.scheduled <= m.tick+m.delay {
+			input := m.input()
+
+			m.inputs[m.local][m.scheduled] = input
+			if err := m.conn.SendInput(m.scheduled, uint16(input)); err != nil {
+				m.Err = err
+				return
+			}
+
+			m.scheduled++
+		}
+
+		inputs, ok := m.inputsAt(m.tick)
+		if !ok {
+			break
+		}
+
+		m.advance(inputs)
+		m.accumulator -= tickDuration
+	}
+
+	m.accumulator = math.Min(m.accumulator, maxFrameTime)
+}
+
+func (m *Match) inputsAt(tick uint64) ([2]Input, bool) {
+	var inputs [2]Input
+
+	if tick < m.delay {
+		return inputs, true
+	}
+
+	for i := range inputs {
+		input, ok := m.inputs[i][tick]
+		if !ok {
+			return inputs, false
+		}
+
+		inputs[i] = input
+	}
+
+	return inputs, true
+}
+
+func (m *Match) advance(inputs [2]Input) {
+	for i, b := range m.boards {
+		b.Tick(inputs[i])
+	}
+
+	for i, b := range m.boards {
+		if rows := b.Levels[b.Level].ClearedRows(); rows > 0 {
+			other := m.boards[1-i]
+			other.Levels[other.Level].AddGarbage(rows, other.rng)
+		}
+	}
+
+	for i := range m.inputs {
+		delete(m.inputs[i], m.tick)
+	}
+
+	m.tick++
+
+	if m.tick%hashInterval == 0 {
+		m.hashes[m.tick] = m.boards[0].StateHash() ^ m.boards[1].StateHash()*31
+		if err := m.conn.SendHash(m.tick, m.hashes[m.tick]); err != nil {
+			m.Err = err
+			return
+		}
+
+		m.checkHash(m.tick)
+	}
+}
+
+func (m *Match) receive() {
+	remote := 1 - m.local
+
+	for {
+		select {
+		case msg, ok := <-m.conn.Messages():
+			if !ok {
+				m.Err = fmt.Errorf("opponent disconnected: %w", m.conn.Err())
+				return
+			}
+
+			switch msg.Kind {
+			case netplay.MessageInput:
+				m.inputs[remote][msg.Tick] = Input(msg.Value)
+			case netplay.MessageHash:
+				m.remoteHashes[msg.Tick] = msg.Value
+				m.checkHash(msg.Tick)
+			}
+		default:
+			return
+		}
+	}
+}
+
+func (m *Match) checkHash(tick uint64) {
+	local, ok := m.hashes[tick]
+	if !ok {
+		return
+	}
+
+	remote, ok := m.remoteHashes[tick]
+	if !ok {
+		return
+	}
+
+	if local != remote {
+		m.Err = fmt.Errorf("%w at tick %d", ErrDesync, tick)
+	}
+
+	delete(m.hashes, tick)
+	delete(m.remoteHashes, tick)
+}
+
+func (m *Match) finish(board *Game, won bool) {
+	if m.Winner != noWinner {
+		return
+	}
+
+	player := 0
+	if m.boards[1] == board {
+		player = 1
+	}
+
+	if !won {
+		player = 1 - player
+	}
+
+	m.Winner = player
+
+	for _, b := range m.boards {
+		b.State = StateWin
+	}
+}
+
+func (m *Match) Status() string {
+	switch {
+	case m.Err != nil:
+		return m.Err.Error()
+	case m.Winner == m.local:
+		return "You WON!!!"
+	case m.Winner != noWinner:
+		return "You LOST"
+	}
+
+	return ""
+}
+
+func (m *Match) Opponent() *Game {
+	return m.boards[1-m.local]
+}
+
+func (m *Match) Close() error {
+	return m.conn.Close()
+}
+
+func (g *Game) StartMatch(conn *netplay.Conn, player int, config netplay.Config) error {
+	m, err := NewMatch(g, conn, player, config)
+	if err != nil {
+		return fmt.Errorf("failed to create match: %w", err)
+	}
+
+	g.match = m
+
+	return nil
+}
+
+func (g *Game) StateHash() uint64 {
+	buf := make([]byte, 0, 1024)
+	putVec := func(v mgl32.Vec2) {
+		buf = binary.LittleEndian.AppendUint32(buf, math.Float32bits(v.X()))
+		buf = binary.LittleEndian.AppendUint32(buf, math.Float32bits(v.Y()))
+	}
+
+	buf = binary.LittleEndian.AppendUint64(buf, g.tick)
+	putVec(g.ball.Position)
+	putVec(g.ball.Velocity)
+
+	for _, p := range g.activePlayers() {
+		putVec(p.Paddle.Position)
+		buf = binary.LittleEndian.AppendUint32(buf, p.Lives)
+		buf = binary.LittleEndian.AppendUint64(buf, uint64(p.Score))
+	}
+
+	for _, brick := range g.Levels[g.Level].Bricks {
+		putVec(brick.Position)
+		if brick.Destroyed {
+			buf = append(buf, 1)
+		} else {
+			buf = append(buf, 0)
+		}
+	}
+
+	buf = binary.LittleEndian.AppendUint32(buf, uint32(len(g.PowerUps)))
+
+	h := fnv.New64a()
+	h.Write(buf)
+
+	return h.Sum64()
+}
+
+func (g *Game) drawMiniBoard(b *Game, origin mgl32.Vec2, scale float32) {
+	var (
+		tex        = g.Resources.RegionOrFallback("block")
+		panelSize  = mgl32.Vec2{float32(b.Width), float32(b.Height)}.Mul(scale)
+		panelColor = mgl32.Vec3{0.1, 0.1, 0.15}
+	)
+
+	g.Renderer.Begin()
+	defer g.Renderer.End()
+
+	g.Renderer.Draw(LayerBackground, tex, origin, panelSize, 0, panelColor)
+
+	draw := func(o *Object) {
+		g.Renderer.Draw(LayerBoard, tex, origin.Add(o.Position.Mul(scale)), o.Size.Mul(scale), 0, o.Color)
+	}
+
+	for _, brick := range b.Levels[b.Level].Bricks {
+		if !brick.Destroyed {
+			draw(&brick.Object)
+		}
+	}
+
+	for _, p := range b.activePlayers() {
+		draw(p.Paddle)
+	}
+
+	draw(&b.ball.Object)
+}
@@ -0,0 +1,168 @@
+package game
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"breakout/src/netplay"
+)
+
+const (
+	matchTestTicks   = 600
+	matchTestTimeout = 30 * time.Second
+)
+
+// peer is one side of a loopback match.
+type peer struct {
+	game  *Game
+	match *Match
+}
+
+func newPeer(t *testing.T, conn *netplay.Conn, player int, config netplay.Config) *peer {
+	t.Helper()
+
+	g := NewGame(800, 600)
+	if err := g.InitSimulation(); err != nil {
+		t.Fatalf("failed to init simulation: %v", err)
+	}
+
+	if err := g.StartMatch(conn, player, config); err != nil {
+		t.Fatalf("failed to start match: %v", err)
+	}
+
+	// The scripts differ per player so a swapped input would show up in the
+	// state.
+	var calls int
+	g.match.input = func() Input {
+		calls++
+
+		switch {
+		case calls < 10:
+			return InputLaunch
+		case (calls/(40+20*player))%2 == 0:
+			return InputLeft
+		}
+
+		return InputRight
+	}
+
+	// Clearing a row of the host board on both sides sends garbage to the
+	// joining player on the first tick.
+	host := g.match.boards[0]
+	for _, brick := range host.Levels[host.Level].Bricks {
+		if brick.Row == 0 && !brick.IsSolid {
+			brick.Destroyed = true
+		}
+	}
+
+	return &peer{game: g, match: g.match}
+}
+
+// run advances the match one tick per update until it reaches ticks or ends.
+func (p *peer) run(ticks uint64) error {
+	deadline := time.Now().Add(matchTestTimeout)
+
+	for p.match.tick < ticks && !p.match.Over() {
+		if time.Now().After(deadline) {
+			return errTimeout
+		}
+
+		p.match.accumulator = 0
+		before := p.match.tick
+		p.match.Update(tickDuration)
+
+		if p.match.tick == before {
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	return p.match.Err
+}
+
+var errTimeout = errors.New("timed out waiting for the opponent")
+
+func garbage(g *Game) int {
+	count := 0
+	for _, brick := range g.Levels[g.Level].Bricks {
+		if brick.Spawned && brick.Row == -1 {
+			count++
+		}
+	}
+
+	return count
+}
+
+func TestMatchLoopback(t *testing.T) {
+	config := netplay.Config{Seed: 7, Level: 0, Delay: 3}
+
+	listener, err := netplay.Listen("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	type accepted struct {
+		conn *netplay.Conn
+		err  error
+	}
+	hosted := make(chan accepted, 1)
+	go func() {
+		conn, err := listener.Accept(config)
+		hosted <- accepted{conn, err}
+	}()
+
+	joinConn, joinConfig, err := netplay.Join(listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := <-hosted
+	if h.err != nil {
+		t.Fatal(h.err)
+	}
+
+	if joinConfig != config {
+		t.Fatalf("joined with config %+v, want %+v", joinConfig, config)
+	}
+
+	host := newPeer(t, h.conn, 0, config)
+	join := newPeer(t, joinConn, 1, joinConfig)
+	defer host.match.Close()
+	defer join.match.Close()
+
+	errs := make(chan error, 2)
+	for _, p := range []*peer{host, join} {
+		go func(p *peer) { errs <- p.run(matchTestTicks) }(p)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := <-errs; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if host.match.tick != join.match.tick {
+		t.Fatalf("host stopped at tick %d, joiner at %d", host.match.tick, join.match.tick)
+	}
+
+	for i := range host.match.boards {
+		if a, b := host.match.boards[i].StateHash(), join.match.boards[i].StateHash(); a != b {
+			t.Errorf("board %d differs after %d ticks: %x != %x", i, host.match.tick, a, b)
+		}
+	}
+
+	if host.match.Winner != join.match.Winner {
+		t.Errorf("host sees winner %d, joiner %d", host.match.Winner, join.match.Winner)
+	}
+
+	for name, p := range map[string]*peer{"host": host, "joiner": join} {
+		if n := garbage(p.match.boards[1]); n == 0 {
+			t.Errorf("%s: no garbage reached the joining board", name)
+		}
+	}
+
+	if a, b := garbage(host.match.boards[1]), garbage(join.match.boards[1]); a != b {
+		t.Errorf("garbage differs: host %d, joiner %d", a, b)
+	}
+}
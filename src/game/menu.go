@@ -0,0 +1,255 @@
+package game
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/go-gl/mathgl/mgl32"
+
+	"breakout/src/controls"
+	"breakout/src/render"
+	"breakout/src/ui"
+)
+
+// menuListRows is how many levels the level select shows at once.
+const menuListRows = 8
+
+type screenKind int
+
+const (
+	screenNone screenKind = iota
+	screenMenu
+	screenLevels
+	screenSettings
+	screenPause
+)
+
+// activeScreen is the menu screen the game state shows, if any.
+func (g *Game) activeScreen() screenKind {
+	switch {
+	case g.State == StateMenu:
+		return screenMenu
+	case g.State == StateLevelSelect:
+		return screenLevels
+	case g.State == StateSettings:
+		return screenSettings
+	case g.State == StateActive && g.paused:
+		return screenPause
+	}
+
+	return screenNone
+}
+
+// currentScreen builds the screen again whenever the game moves to another
+// one, so it starts from the current state.
+func (g *Game) currentScreen() *ui.Screen {
+	kind := g.activeScreen()
+	if kind == g.screenShown && g.screen != nil {
+		return g.screen
+	}
+
+	g.screenShown = kind
+
+	switch kind {
+	case screenMenu:
+		g.screen = g.buildMenu()
+	case screenLevels:
+		g.screen = g.buildLevelSelect()
+	case screenSettings:
+		g.screen = g.buildSettings()
+	case screenPause:
+		g.screen = g.buildPause()
+	default:
+		g.screen = nil
+	}
+
+	return g.screen
+}
+
+func (g *Game) updateScreen() {
+	if g.ui == nil {
+		return
+	}
+
+	if screen := g.currentScreen(); screen != nil {
+		screen.Update(g.ui, g.canvas(), g.menuInput())
+	}
+}
+
+func (g *Game) drawScreen() {
+	if g.ui == nil {
+		return
+	}
+
+	if screen := g.currentScreen(); screen != nil {
+		screen.Draw(g.ui, g.canvas())
+	}
+}
+
+func (g *Game) canvas() ui.Rect {
+	return ui.Rect{Size: mgl32.Vec2{float32(g.Width), float32(g.Height)}}
+}
+
+// menuInput maps the menu actions, both players' left and right and the
+// cursor onto the screen input.
+func (g *Game) menuInput() ui.Input {
+	c := g.Controls
+	in := ui.Input{
+		Up:       c.Pressed(0, controls.MenuUp),
+		Down:     c.Pressed(0, controls.MenuDown),
+		Left:     c.Pressed(0, controls.MoveLeft) || c.Pressed(1, controls.MoveLeft),
+		Right:    c.Pressed(0, controls.MoveRight) || c.Pressed(1, controls.MoveRight),
+		Activate: c.Pressed(0, controls.Confirm),
+		Back:     c.Pressed(0, controls.Pause),
+		Click:    c.Clicked(),
+	}
+
+	if x, y, ok := c.Cursor(); ok {
+		in.Cursor = mgl32.Vec2{x * float32(g.Width), y * float32(g.Height)}
+		in.HasCursor = true
+	}
+
+	return in
+}
+
+func (g *Game) menuHint() string {
+	return fmt.Sprintf("%s/%s select, %s/%s change, %s choose, %s back",
+		g.keyLabel(controls.MenuUp), g.keyLabel(controls.MenuDown),
+		g.keyLabel(controls.MoveLeft), g.keyLabel(controls.MoveRight),
+		g.keyLabel(controls.Confirm), g.keyLabel(controls.Pause))
+}
+
+// compactTheme fits the long settings list on the smallest canvas.
+func compactTheme() *ui.Theme {
+	t := ui.DefaultTheme()
+	t.TextScale = 0.6
+	t.Padding = 4
+	t.Spacing = 2
+
+	return t
+}
+
+func (g *Game) buildMenu() *ui.Screen {
+	inMatch := g.match != nil
+
+	start := ui.NewButton("Start", func() { g.State = StateActive })
+
+	level := ui.NewButton(fmt.Sprintf("Level: %s", g.levelName(g.Level)), func() { g.State = StateLevelSelect })
+	level.Disabled = inMatch
+
+	modes := make([]string, 0, modeCount)
+	for m := Mode(0); m < modeCount; m++ {
+		modes = append(modes, m.String())
+	}
+
+	current := func() string { return g.Mode.String() }
+	mode := ui.NewChoice("Mode", func() []string { return modes }, current, func(name string) {
+		for m := Mode(0); m < modeCount; m++ {
+			if m.String() == name {
+				g.Mode = m
+			}
+		}
+
+		g.ApplyMode()
+	})
+
+	mouse := ui.NewToggle("Mouse control",
+		func() bool { return g.Controls.Mouse.Enabled },
+		func(on bool) { g.Controls.Mouse.Enabled = on })
+
+	rebind := ui.NewButton("Controls", g.openRebind)
+	rebind.Disabled = inMatch
+
+	options := ui.NewButton("Settings", g.openSettings)
+	options.Disabled = inMatch
+
+	panel := ui.NewPanel(ui.VBox(start, level, mode, mouse, rebind, options))
+	panel.MinWidth = 420
+
+	items := []ui.Widget{ui.NewTitle("BREAKOUT", 3), &ui.Spacer{Height: 20}, panel}
+
+	grey := render.TextStyle{Color: mgl32.Vec3{0.7, 0.7, 0.7}}
+	items = append(items, &ui.Label{Text: g.menuHint(), Scale: 0.6, Align: render.AlignCenter, Style: &grey})
+
+	for i := 0; i < controls.Players; i++ {
+		if name, ok := g.Controls.Gamepad(i); ok {
+			items = append(items, &ui.Label{Text: fmt.Sprintf("P%d gamepad: %s", i+1, name), Scale: 0.6, Align: render.AlignCenter, Style: &grey})
+		}
+	}
+
+	return ui.NewScreen(ui.VBox(items...))
+}
+
+func (g *Game) buildLevelSelect() *ui.Screen {
+	items := make([]string, 0, len(g.Levels))
+	for i := range g.Levels {
+		items = append(items, g.levelName(i))
+	}
+
+	rows := menuListRows
+	if len(items) < rows {
+		rows = len(items)
+	}
+
+	list := ui.NewList(items, g.Level, rows)
+	list.OnChange = func(i int) { g.Level = i }
+	list.OnActivate = func(int) { g.State = StateMenu }
+
+	back := ui.NewButton("Back", func() { g.State = StateMenu })
+
+	panel := ui.NewPanel(ui.VBox(ui.NewTitle("Select level", 1), list, back))
+	panel.MinWidth = 320
+
+	s := ui.NewScreen(panel)
+	s.OnBack = func() { g.State = StateMenu }
+	s.Focus(list)
+
+	return s
+}
+
+func (g *Game) buildPause() *ui.Screen {
+	resume := ui.NewButton("Resume", func() { g.paused = false })
+	restart := ui.NewButton("Restart level", g.restartLevel)
+	quit := ui.NewButton("Main menu", g.returnToMenu)
+
+	panel := ui.NewPanel(ui.VBox(ui.NewTitle("PAUSED", 1.5), &ui.Spacer{Height: 8}, resume, restart, quit))
+	panel.MinWidth = 280
+
+	s := ui.NewScreen(panel)
+	s.OnBack = func() { g.paused = false }
+
+	return s
+}
+
+// levelName is the number and file name of a level, with a note when a
+// boss waits in it.
+func (g *Game) levelName(index int) string {
+	name := fmt.Sprint(index + 1)
+	if g.manifest != nil && index < len(g.manifest.Levels) {
+		file := path.Base(g.manifest.Levels[index])
+		name += "  " + strings.TrimSuffix(file, path.Ext(file))
+	}
+
+	if index < len(g.Levels) && g.Levels[index].BossName != "" {
+		name += " (boss)"
+	}
+
+	return name
+}
+
+func (g *Game) restartLevel() {
+	g.confuse, g.chaos = false, false
+	g.PowerUps = g.PowerUps[:0]
+	g.ResetLevel()
+	g.ResetPlayers(0)
+	g.paused = false
+}
+
+// returnToMenu abandons the game in progress.
+func (g *Game) returnToMenu() {
+	g.restartLevel()
+	g.ResetScores()
+	g.idleTime = 0
+	g.State = StateMenu
+}
@@ -0,0 +1,134 @@
+package game
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+type MotionKind int
+
+const (
+	OscillateMotion MotionKind = iota
+	OrbitMotion
+	ConveyorMotion
+)
+
+type Motion struct {
+	Kind      MotionKind
+	Amplitude float32
+	Period    float32
+	Speed     float32
+}
+
+// Offset returns the displacement from the brick origin and the brick
+// velocity at time t. Conveyor offsets wrap around a lane of laneWidth.
+func (m *Motion) Offset(t float64, originX, width, laneWidth float32) (mgl32.Vec2, mgl32.Vec2) {
+	switch m.Kind {
+	case OscillateMotion:
+		omega := 2 * math.Pi / float64(m.Period)
+		x := m.Amplitude * float32(math.Sin(omega*t))
+		vx := m.Amplitude * float32(omega*math.Cos(omega*t))
+
+		return mgl32.Vec2{x, 0}, mgl32.Vec2{vx, 0}
+	case OrbitMotion:
+		omega := 2 * math.Pi / float64(m.Period)
+		sin, cos := math.Sincos(omega * t)
+		offset := mgl32.Vec2{float32(cos) - 1, float32(sin)}.Mul(m.Amplitude)
+		velocity := mgl32.Vec2{float32(-sin), float32(cos)}.Mul(m.Amplitude * float32(omega))
+
+		return offset, velocity
+	case ConveyorMotion:
+		span := float64(laneWidth + width)
+		x := math.Mod(float64(originX+width)+float64(m.Speed)*t, span)
+		if x < 0 {
+			x += span
+		}
+
+		return mgl32.Vec2{float32(x) - width - originX, 0}, mgl32.Vec2{m.Speed, 0}
+	}
+
+	return mgl32.Vec2{0, 0}, mgl32.Vec2{0, 0}
+}
+
+type motionRule struct {
+	row    int
+	column int
+	motion Motion
+}
+
+func (r *motionRule) matches(row, column int) bool {
+	return r.row == row && (r.column < 0 || r.column == column)
+}
+
+// Motion directives look like "@motion row=2 oscillate 80 2",
+// "@motion tile=4,1 orbit 20 3" or "@motion row=0 conveyor 60".
+func parseMotionRule(line string) (motionRule, error) {
+	fields := strings.Fields(line)
+	rule := motionRule{column: -1}
+
+	if len(fields) < 3 || fields[0] != "@motion" {
+		return rule, fmt.Errorf("malformed directive %q", line)
+	}
+
+	target, value, ok := strings.Cut(fields[1], "=")
+	if !ok {
+		return rule, fmt.Errorf("malformed target %q", fields[1])
+	}
+
+	var err error
+
+	switch target {
+	case "row":
+		rule.row, err = strconv.Atoi(value)
+	case "tile":
+		column, row, found := strings.Cut(value, ",")
+		if !found {
+			return rule, fmt.Errorf("malformed tile %q", value)
+		}
+
+		if rule.column, err = strconv.Atoi(column); err == nil {
+			rule.row, err = strconv.Atoi(row)
+		}
+	default:
+		return rule, fmt.Errorf("unknown target %q", target)
+	}
+	if err != nil {
+		return rule, fmt.Errorf("failed to parse target: %w", err)
+	}
+
+	params := make([]float32, 0, 2)
+	for _, field := range fields[3:] {
+		param, err := strconv.ParseFloat(field, 32)
+		if err != nil {
+			return rule, fmt.Errorf("failed to parse parameter: %w", err)
+		}
+
+		params = append(params, float32(param))
+	}
+
+	switch fields[2] {
+	case "oscillate", "orbit":
+		if len(params) != 2 || params[1] <= 0 {
+			return rule, fmt.Errorf("%s expects an amplitude and a positive period", fields[2])
+		}
+
+		rule.motion = Motion{Kind: OscillateMotion, Amplitude: params[0], Period: params[1]}
+		if fields[2] == "orbit" {
+			rule.motion.Kind = OrbitMotion
+		}
+	case "conveyor":
+		if len(params) != 1 {
+			return rule, fmt.Errorf("conveyor expects a speed")
+		}
+
+		rule.motion = Motion{Kind: ConveyorMotion, Speed: params[0]}
+	default:
+		return rule, fmt.Errorf("unknown motion %q", fields[2])
+	}
+
+	return rule, nil
+}
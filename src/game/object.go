@@ -7,6 +7,12 @@ import (
 	"breakout/src/texture"
 )
 
+// Layers order the sprites of a batch, lower layers are drawn first.
+const (
+	LayerBackground = -1
+	LayerBoard      = 0
+)
+
 type Object struct {
 	Position  mgl32.Vec2
 	Size      mgl32.Vec2
@@ -15,13 +21,14 @@ type Object struct {
 	Rotation  float32
 	IsSolid   bool
 	Destroyed bool
+	Layer     int
 
-	Sprite *texture.Texture2D
+	Sprite *texture.Region
 }
 
 func NewObject(
 	position, size mgl32.Vec2,
-	sprite *texture.Texture2D,
+	sprite *texture.Region,
 	color *mgl32.Vec3,
 	velocity *mgl32.Vec2,
 ) *Object {
@@ -46,5 +53,5 @@ func NewObject(
 }
 
 func (g *Object) Draw(renderer *render.SpriteRenderer) {
-	renderer.DrawSprite(g.Sprite, &g.Position, &g.Size, g.Rotation, &g.Color)
+	renderer.Draw(g.Layer, g.Sprite, g.Position, g.Size, g.Rotation, g.Color)
 }
@@ -1,54 +1,157 @@
 package game
 
 import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"math"
 	"math/rand"
+	"sort"
 	"unsafe"
 
 	"github.com/go-gl/gl/v3.3-core/gl"
 	"github.com/go-gl/mathgl/mgl32"
 
+	"breakout/src/render"
+	"breakout/src/resource"
 	"breakout/src/shader"
 	"breakout/src/texture"
 )
 
+// An instance is the position, color and size of a particle.
+const instanceFloats = 7
+
+const (
+	BlendAdditive = "additive"
+	BlendAlpha    = "alpha"
+)
+
+// EmitterDef describes how an emitter spawns particles and how they change
+// over their life. Ranges are [min, max] and a value is picked uniformly
+// between them for every particle.
+type EmitterDef struct {
+	Texture string `json:"texture"`
+	Blend   string `json:"blend"`
+
+	// Rate is the particles per second of Emit, Burst the particles of Burst.
+	Rate  float32 `json:"rate"`
+	Burst int     `json:"burst"`
+
+	Lifetime mgl32.Vec2 `json:"lifetime"`
+
+	// Particles leave in a cone around Direction, in degrees with 90
+	// pointing down the screen, Spread degrees wide.
+	Direction float32    `json:"direction"`
+	Spread    float32    `json:"spread"`
+	Speed     mgl32.Vec2 `json:"speed"`
+
+	// Inherit is the part of the source velocity a particle starts with.
+	Inherit float32 `json:"inherit"`
+
+	// Jitter is how far from the source a particle may spawn.
+	Jitter float32 `json:"jitter"`
+
+	Gravity mgl32.Vec2 `json:"gravity"`
+	Drag    float32    `json:"drag"`
+
+	// Color and Size are keys spread evenly over the life of a particle.
+	Color      []mgl32.Vec4 `json:"color"`
+	Size       []float32    `json:"size"`
+	Brightness mgl32.Vec2   `json:"brightness"`
+}
+
+func LoadEmitterDef(fsys fs.FS, fileName string) (*EmitterDef, error) {
+	data, err := fs.ReadFile(fsys, fileName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read emitter file: %w", err)
+	}
+
+	def := EmitterDef{
+		Texture:    "particle",
+		Blend:      BlendAdditive,
+		Brightness: mgl32.Vec2{1, 1},
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+
+	if err := decoder.Decode(&def); err != nil {
+		return nil, fmt.Errorf("failed to decode emitter file: %w", err)
+	}
+
+	if def.Blend != BlendAdditive && def.Blend != BlendAlpha {
+		return nil, fmt.Errorf("unknown blend mode %q", def.Blend)
+	}
+
+	if def.Lifetime.X() <= 0 || def.Lifetime.Y() < def.Lifetime.X() {
+		return nil, fmt.Errorf("emitter lifetime should be a positive [min, max] range")
+	}
+
+	if len(def.Color) == 0 || len(def.Size) == 0 {
+		return nil, fmt.Errorf("emitter color and size need at least one key")
+	}
+
+	return &def, nil
+}
+
 type Particle struct {
 	Position mgl32.Vec2
 	Velocity mgl32.Vec2
-	Color    mgl32.Vec4
+	Tint     mgl32.Vec3
+	Age      float32
 	Life     float32
 }
 
-func NewParticle() Particle {
-	return Particle{
-		Position: mgl32.Vec2{0, 0},
-		Velocity: mgl32.Vec2{0, 0},
-		Color:    mgl32.Vec4{1, 1, 1, 1},
-		Life:     0,
-	}
+// Emitter spawns particles from one definition and keeps the live ones.
+type Emitter struct {
+	Name string
+	Def  *EmitterDef
+
+	particles []Particle
+	region    *texture.Region
+	pending   float32
 }
 
-type ParticleGenerator struct {
-	particles        []Particle
-	amount           int
-	lastUsedParticle int
+// ParticleSystem owns the named emitters and draws their particles. All of
+// them share a budget of amount live particles.
+type ParticleSystem struct {
+	emitters map[string]*Emitter
+	order    []*Emitter
+	amount   int
+	live     int
 
-	s   *shader.Shader
-	t   *texture.Texture2D
-	vao uint32
+	s         *shader.Shader
+	resources *resource.Manager
+	vao       uint32
+	vbo       uint32
+	instances []float32
 }
 
-func NewParticleGenerator(s *shader.Shader, t *texture.Texture2D, amount int) *ParticleGenerator {
-	p := &ParticleGenerator{amount: amount, s: s, t: t}
-	p.init()
+func NewParticleSystem(s *shader.Shader, resources *resource.Manager, amount int) *ParticleSystem {
+	ps := &ParticleSystem{
+		emitters:  make(map[string]*Emitter),
+		amount:    amount,
+		s:         s,
+		resources: resources,
+	}
+	ps.init()
+
+	return ps
+}
 
-	return p
+// Cleanup frees the buffers of the system.
+func (ps *ParticleSystem) Cleanup() {
+	gl.DeleteVertexArrays(1, &ps.vao)
+	gl.DeleteBuffers(1, &ps.vbo)
 }
 
-func (pg *ParticleGenerator) init() {
+func (ps *ParticleSystem) init() {
 	var (
 		vbo      uint32
 		fType    float32
 		fSize    = int(unsafe.Sizeof(fType))
+		stride   = int32(instanceFloats * fSize)
 		vertices = []float32{
 			0, 1, 0, 1,
 			1, 0, 1, 0,
@@ -60,9 +163,9 @@ func (pg *ParticleGenerator) init() {
 		}
 	)
 
-	gl.GenVertexArrays(1, &pg.vao)
+	gl.GenVertexArrays(1, &ps.vao)
 	gl.GenBuffers(1, &vbo)
-	gl.BindVertexArray(pg.vao)
+	gl.BindVertexArray(ps.vao)
 
 	gl.BindBuffer(gl.ARRAY_BUFFER, vbo)
 	gl.BufferData(
@@ -74,73 +177,211 @@ func (pg *ParticleGenerator) init() {
 
 	gl.EnableVertexAttribArray(0)
 	gl.VertexAttribPointer(0, 4, gl.FLOAT, false, int32(4*fSize), nil)
+
+	// The instance buffer is refilled every frame with the live particles,
+	// its attributes advance once per quad instead of once per vertex.
+	gl.GenBuffers(1, &ps.vbo)
+	gl.BindBuffer(gl.ARRAY_BUFFER, ps.vbo)
+	gl.BufferData(gl.ARRAY_BUFFER, ps.amount*instanceFloats*fSize, nil, gl.STREAM_DRAW)
+
+	gl.EnableVertexAttribArray(1)
+	gl.VertexAttribPointer(1, 2, gl.FLOAT, false, stride, nil)
+	gl.VertexAttribDivisor(1, 1)
+	gl.EnableVertexAttribArray(2)
+	gl.VertexAttribPointerWithOffset(2, 4, gl.FLOAT, false, stride, uintptr(2*fSize))
+	gl.VertexAttribDivisor(2, 1)
+	gl.EnableVertexAttribArray(3)
+	gl.VertexAttribPointerWithOffset(3, 1, gl.FLOAT, false, stride, uintptr(6*fSize))
+	gl.VertexAttribDivisor(3, 1)
+
 	gl.BindVertexArray(0)
+	gl.BindBuffer(gl.ARRAY_BUFFER, 0)
+}
 
-	pg.particles = make([]Particle, 0, pg.amount)
-	for i := 0; i < pg.amount; i++ {
-		pg.particles = append(pg.particles, NewParticle())
+// SetEmitter adds an emitter or replaces the definition of an existing one,
+// which keeps its live particles.
+func (ps *ParticleSystem) SetEmitter(name string, def *EmitterDef) {
+	e, ok := ps.emitters[name]
+	if !ok {
+		e = &Emitter{Name: name}
+		ps.emitters[name] = e
+		ps.order = append(ps.order, e)
+		sort.Slice(ps.order, func(i, j int) bool { return ps.order[i].Name < ps.order[j].Name })
 	}
+
+	e.Def = def
+	e.region = ps.resources.RegionOrFallback(def.Texture)
 }
 
-func (pg *ParticleGenerator) Update(dt float64, o *Object, newParticles int, offset mgl32.Vec2) {
-	for i := 0; i < newParticles; i++ {
-		unusedParticle := pg.firstUnusedParticle()
-		pg.respawnParticle(&pg.particles[unusedParticle], o, offset)
+// Emit spawns particles at the rate of the emitter over dt seconds. Unknown
+// emitters are ignored, so a missing preset only loses its effect.
+func (ps *ParticleSystem) Emit(name string, dt float64, position, velocity mgl32.Vec2) {
+	e, ok := ps.emitters[name]
+	if !ok {
+		return
 	}
 
-	for i := range pg.particles {
-		p := &pg.particles[i]
-		p.Life -= float32(dt)
+	e.pending += e.Def.Rate * float32(dt)
+	for ; e.pending >= 1; e.pending-- {
+		ps.spawn(e, position, velocity, mgl32.Vec3{1, 1, 1})
+	}
+}
 
-		if p.Life > 0 {
-			p.Position.Sub(p.Velocity.Mul(float32(dt)))
-			p.Color[3] -= float32(dt) * 2.5
-		}
+// Burst spawns the burst count of the emitter at once, tinted with color.
+func (ps *ParticleSystem) Burst(name string, position mgl32.Vec2, color mgl32.Vec3) {
+	e, ok := ps.emitters[name]
+	if !ok {
+		return
+	}
+
+	for i := 0; i < e.Def.Burst; i++ {
+		ps.spawn(e, position, mgl32.Vec2{}, color)
 	}
 }
 
-func (pg *ParticleGenerator) firstUnusedParticle() int {
-	for i := pg.lastUsedParticle; i < pg.amount; i++ {
-		if pg.particles[i].Life <= 0 {
-			pg.lastUsedParticle = i
-			return i
-		}
+func (ps *ParticleSystem) spawn(e *Emitter, position, velocity mgl32.Vec2, tint mgl32.Vec3) {
+	if ps.live >= ps.amount {
+		return
 	}
 
-	for i := 0; i < pg.lastUsedParticle; i++ {
-		if pg.particles[i].Life <= 0 {
-			pg.lastUsedParticle = i
-			return i
+	def := e.Def
+	angle := mgl32.DegToRad(def.Direction + (rand.Float32()-0.5)*def.Spread)
+	sin, cos := math.Sincos(float64(angle))
+	speed := between(def.Speed)
+	jitter := mgl32.Vec2{rand.Float32()*2 - 1, rand.Float32()*2 - 1}.Mul(def.Jitter)
+
+	e.particles = append(e.particles, Particle{
+		Position: position.Add(jitter),
+		Velocity: mgl32.Vec2{float32(cos) * speed, float32(sin) * speed}.Add(velocity.Mul(def.Inherit)),
+		Tint:     tint.Mul(between(def.Brightness)),
+		Life:     between(def.Lifetime),
+	})
+	ps.live++
+}
+
+func (ps *ParticleSystem) Update(dt float64) {
+	step := float32(dt)
+
+	for _, e := range ps.order {
+		var (
+			drag    = float32(math.Max(0, float64(1-e.Def.Drag*step)))
+			gravity = e.Def.Gravity.Mul(step)
+		)
+
+		for i := 0; i < len(e.particles); {
+			p := &e.particles[i]
+			p.Age += step
+
+			if p.Age >= p.Life {
+				last := len(e.particles) - 1
+				e.particles[i] = e.particles[last]
+				e.particles = e.particles[:last]
+				ps.live--
+				continue
+			}
+
+			p.Velocity = p.Velocity.Add(gravity).Mul(drag)
+			p.Position = p.Position.Add(p.Velocity.Mul(step))
+			i++
 		}
 	}
-
-	pg.lastUsedParticle = 0
-	return 0
 }
 
-func (pg *ParticleGenerator) respawnParticle(p *Particle, o *Object, offset mgl32.Vec2) {
-	random := float32((rand.Int()%100)-50) / 10
-	rColor := 0.5 + float32(rand.Int()%100)/100
-	p.Position = o.Position.Add(mgl32.Vec2{random, random}).Add(offset)
-	p.Color = mgl32.Vec4{rColor, rColor, rColor, 1}
-	p.Life = 1
-	p.Velocity = o.Velocity.Mul(0.1)
+// Clear removes every live particle, when a level starts over for example.
+func (ps *ParticleSystem) Clear() {
+	for _, e := range ps.order {
+		e.particles = e.particles[:0]
+		e.pending = 0
+	}
+
+	ps.live = 0
 }
 
-func (pg *ParticleGenerator) Draw() {
-	gl.BlendFunc(gl.SRC_ALPHA, gl.ONE)
-	pg.s.Use()
+// Draw draws the particles of every emitter with one instanced call each.
+func (ps *ParticleSystem) Draw() {
+	if ps.live == 0 {
+		return
+	}
+
+	ps.s.Use()
+	gl.ActiveTexture(gl.TEXTURE0)
+	gl.BindVertexArray(ps.vao)
+	gl.BindBuffer(gl.ARRAY_BUFFER, ps.vbo)
 
-	for i := range pg.particles {
-		if pg.particles[i].Life > 0 {
-			pg.s.SetVector2fv("offset", &pg.particles[i].Position, false)
-			pg.s.SetVector4fv("color", &pg.particles[i].Color, false)
-			pg.t.Bind()
-			gl.BindVertexArray(pg.vao)
-			gl.DrawArrays(gl.TRIANGLES, 0, 6)
-			gl.BindVertexArray(0)
+	for _, e := range ps.order {
+		if len(e.particles) == 0 {
+			continue
 		}
+
+		ps.instances = ps.instances[:0]
+		for i := range e.particles {
+			p := &e.particles[i]
+			t := p.Age / p.Life
+			color := sampleColor(e.Def.Color, t)
+			ps.instances = append(ps.instances,
+				p.Position.X(), p.Position.Y(),
+				color.X()*p.Tint.X(), color.Y()*p.Tint.Y(), color.Z()*p.Tint.Z(), color.W(),
+				sampleSize(e.Def.Size, t),
+			)
+		}
+
+		if e.Def.Blend == BlendAdditive {
+			gl.BlendFunc(gl.SRC_ALPHA, gl.ONE)
+		} else {
+			gl.BlendFunc(gl.SRC_ALPHA, gl.ONE_MINUS_SRC_ALPHA)
+		}
+
+		ps.s.SetVector4fv("uvRect", &e.region.UV, false)
+		e.region.Texture.Bind()
+
+		// Orphaning only the live instances keeps the upload to what is drawn.
+		fSize := int(unsafe.Sizeof(ps.instances[0]))
+		gl.BufferData(gl.ARRAY_BUFFER, len(ps.instances)*fSize, gl.Ptr(ps.instances), gl.STREAM_DRAW)
+		gl.DrawArraysInstanced(gl.TRIANGLES, 0, 6, int32(len(e.particles)))
+		render.CountDraw(len(e.particles))
 	}
 
+	gl.BindBuffer(gl.ARRAY_BUFFER, 0)
+	gl.BindVertexArray(0)
 	gl.BlendFunc(gl.SRC_ALPHA, gl.ONE_MINUS_SRC_ALPHA)
 }
+
+func between(r mgl32.Vec2) float32 {
+	return r.X() + rand.Float32()*(r.Y()-r.X())
+}
+
+// sampleColor interpolates between the keys around t, from 0 at birth to 1
+// at death.
+func sampleColor(keys []mgl32.Vec4, t float32) mgl32.Vec4 {
+	i, f := keyAt(len(keys), t)
+	if f == 0 {
+		return keys[i]
+	}
+
+	return keys[i].Add(keys[i+1].Sub(keys[i]).Mul(f))
+}
+
+func sampleSize(keys []float32, t float32) float32 {
+	i, f := keyAt(len(keys), t)
+	if f == 0 {
+		return keys[i]
+	}
+
+	return keys[i] + (keys[i+1]-keys[i])*f
+}
+
+// keyAt finds the key before t and how far t is towards the next one.
+func keyAt(n int, t float32) (int, float32) {
+	if n == 1 || t <= 0 {
+		return 0, 0
+	}
+
+	if t >= 1 {
+		return n - 1, 0
+	}
+
+	pos := t * float32(n-1)
+	i := int(pos)
+
+	return i, pos - float32(i)
+}
@@ -0,0 +1,173 @@
+package game
+
+import (
+	"runtime"
+	"testing"
+
+	"github.com/go-gl/gl/v3.3-core/gl"
+	"github.com/go-gl/glfw/v3.3/glfw"
+	"github.com/go-gl/mathgl/mgl32"
+
+	"breakout/resources"
+	"breakout/src/assets"
+	"breakout/src/render"
+	"breakout/src/resource"
+)
+
+const benchmarkParticles = 10000
+
+// BenchmarkParticleDraw draws the same load of trail particles one draw call
+// per particle, the way particles were drawn before instancing, and with
+// one instanced call per emitter.
+func BenchmarkParticleDraw(b *testing.B) {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	window := benchmarkContext(b)
+	defer glfw.Terminate()
+	defer window.Destroy()
+
+	ps, cleanup := benchmarkParticleSystem(b)
+	defer cleanup()
+
+	b.Run("per-particle", func(b *testing.B) {
+		benchmarkFrames(b, ps.drawEach)
+	})
+
+	b.Run("instanced", func(b *testing.B) {
+		benchmarkFrames(b, ps.Draw)
+	})
+}
+
+func benchmarkFrames(b *testing.B, draw func()) {
+	render.ResetStats()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		draw()
+		gl.Finish()
+	}
+
+	b.StopTimer()
+
+	stats := render.CurrentStats()
+	b.ReportMetric(float64(stats.DrawCalls)/float64(b.N), "draws/frame")
+	b.ReportMetric(float64(stats.Sprites)/float64(b.N), "instances/frame")
+}
+
+// benchmarkContext opens a hidden window for its OpenGL context, or skips
+// the benchmark where there is no display.
+func benchmarkContext(b *testing.B) *glfw.Window {
+	if err := glfw.Init(); err != nil {
+		b.Skip("no display:", err)
+	}
+
+	glfw.WindowHint(glfw.Visible, glfw.False)
+	glfw.WindowHint(glfw.ContextVersionMajor, 3)
+	glfw.WindowHint(glfw.ContextVersionMinor, 3)
+	glfw.WindowHint(glfw.OpenGLProfile, glfw.OpenGLCoreProfile)
+	glfw.WindowHint(glfw.OpenGLForwardCompatible, glfw.True)
+
+	window, err := glfw.CreateWindow(800, 600, "benchmark", nil, nil)
+	if err != nil {
+		glfw.Terminate()
+		b.Skip("no OpenGL 3.3 context:", err)
+	}
+
+	window.MakeContextCurrent()
+	if err := gl.Init(); err != nil {
+		window.Destroy()
+		glfw.Terminate()
+		b.Skip("failed to init OpenGL:", err)
+	}
+
+	gl.Enable(gl.BLEND)
+
+	return window
+}
+
+// benchmarkParticleSystem fills the trail emitter with live particles
+// spread over the canvas.
+func benchmarkParticleSystem(b *testing.B) (*ParticleSystem, func()) {
+	m, err := assets.LoadManifest(resources.FS, ManifestFile)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	manager := resource.NewManager(resources.FS)
+
+	s := m.Shaders["particle"]
+	if _, err := manager.LoadShader("particle", s.Vertex, s.Fragment, s.Geometry); err != nil {
+		b.Fatal(err)
+	}
+
+	particleShader, err := manager.Shader("particle")
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	projection := mgl32.Ortho2D(0, 800, 600, 0)
+	particleShader.SetInteger("sprite", 0, true)
+	particleShader.SetMatrix4("projection", &projection, false)
+
+	def, err := LoadEmitterDef(resources.FS, m.Particles["trail"])
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	ps := NewParticleSystem(particleShader, manager, benchmarkParticles)
+	ps.SetEmitter("trail", def)
+
+	e := ps.emitters["trail"]
+	for i := 0; i < benchmarkParticles; i++ {
+		position := mgl32.Vec2{float32(i % 800), float32(i / 800 * 40 % 600)}
+		ps.spawn(e, position, mgl32.Vec2{}, mgl32.Vec3{1, 1, 1})
+	}
+
+	return ps, func() {
+		ps.Cleanup()
+		manager.Cleanup()
+	}
+}
+
+// drawEach is the draw path from before instancing: the per-instance
+// attributes are set as constants and every particle is its own draw call.
+func (ps *ParticleSystem) drawEach() {
+	ps.s.Use()
+	gl.ActiveTexture(gl.TEXTURE0)
+	gl.BindVertexArray(ps.vao)
+
+	for location := uint32(1); location <= 3; location++ {
+		gl.DisableVertexAttribArray(location)
+	}
+
+	for _, e := range ps.order {
+		if e.Def.Blend == BlendAdditive {
+			gl.BlendFunc(gl.SRC_ALPHA, gl.ONE)
+		} else {
+			gl.BlendFunc(gl.SRC_ALPHA, gl.ONE_MINUS_SRC_ALPHA)
+		}
+
+		ps.s.SetVector4fv("uvRect", &e.region.UV, false)
+
+		for i := range e.particles {
+			p := &e.particles[i]
+			t := p.Age / p.Life
+			color := sampleColor(e.Def.Color, t)
+
+			gl.VertexAttrib2f(1, p.Position.X(), p.Position.Y())
+			gl.VertexAttrib4f(2, color.X()*p.Tint.X(), color.Y()*p.Tint.Y(), color.Z()*p.Tint.Z(), color.W())
+			gl.VertexAttrib1f(3, sampleSize(e.Def.Size, t))
+			e.region.Texture.Bind()
+			gl.DrawArrays(gl.TRIANGLES, 0, 6)
+			render.CountDraw(1)
+		}
+	}
+
+	for location := uint32(1); location <= 3; location++ {
+		gl.EnableVertexAttribArray(location)
+	}
+
+	gl.BindVertexArray(0)
+	gl.BlendFunc(gl.SRC_ALPHA, gl.ONE_MINUS_SRC_ALPHA)
+}
@@ -0,0 +1,99 @@
+package game
+
+import (
+	"github.com/go-gl/mathgl/mgl32"
+
+	"breakout/src/texture"
+)
+
+type Mode int
+
+const (
+	ModeSingle Mode = iota
+	ModeSideBySide
+	ModeTopBottom
+
+	modeCount = 3
+)
+
+func (m Mode) String() string {
+	switch m {
+	case ModeSideBySide:
+		return "Co-op: side by side"
+	case ModeTopBottom:
+		return "Co-op: top and bottom"
+	}
+
+	return "Single player"
+}
+
+type Input uint16
+
+const (
+	InputLeft Input = 1 << iota
+	InputRight
+	InputLaunch
+
+	// The bits above the flags hold an optional movement speed, fine
+	// enough that analog and mouse input keep their resolution when sent
+	// to versus opponents. The range covers the fastest mouse speed the
+	// settings screen offers over its slowest paddle speed.
+	inputSpeedShift = 3
+	inputSpeedMax   = 1<<(16-inputSpeedShift) - 1
+	inputSpeedRange = 10
+)
+
+// WithSpeed sets movement speed as a multiple of the paddle speed, up to
+// inputSpeedRange. Inputs without a speed move at the paddle speed.
+func (i Input) WithSpeed(multiple float32) Input {
+	speed := Input(mgl32.Clamp(multiple/inputSpeedRange, 0, 1)*inputSpeedMax + 0.5)
+	if speed == 0 {
+		speed = 1
+	}
+
+	return i&(1<<inputSpeedShift-1) | speed<<inputSpeedShift
+}
+
+func (i Input) Speed() float32 {
+	speed := i >> inputSpeedShift
+	if speed == 0 {
+		return 1
+	}
+
+	return float32(speed) / inputSpeedMax * inputSpeedRange
+}
+
+type Player struct {
+	Paddle *Object
+	Lives  uint32
+	Score  int
+	Sticky bool
+	Top    bool
+
+	MinX float32
+	MaxX float32
+}
+
+func NewPlayer(sprite *texture.Region, lives uint32) *Player {
+	return &Player{
+		Paddle: NewObject(mgl32.Vec2{0, 0}, playerSize, sprite, nil, nil),
+		Lives:  lives,
+	}
+}
+
+func (p *Player) Move(dx float32) float32 {
+	x := mgl32.Clamp(p.Paddle.Position.X()+dx, p.MinX, p.MaxX-p.Paddle.Size.X())
+	dx = x - p.Paddle.Position.X()
+	p.Paddle.Position[0] = x
+
+	return dx
+}
+
+func (p *Player) ServePosition(radius float32) mgl32.Vec2 {
+	offset := mgl32.Vec2{p.Paddle.Size.X()/2 - radius, -radius * 2}
+	if p.Top {
+		offset[1] = p.Paddle.Size.Y()
+	}
+
+	return p.Paddle.Position.Add(offset)
+}
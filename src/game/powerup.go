@@ -15,6 +15,7 @@ type PowerUp struct {
 	Type      string
 	Duration  float64
 	Activated bool
+	Player    int
 
 	Object
 }
@@ -24,7 +25,7 @@ func NewPowerUp(
 	color mgl32.Vec3,
 	duration float64,
 	position mgl32.Vec2,
-	tex *texture.Texture2D,
+	tex *texture.Region,
 ) PowerUp {
 	return PowerUp{
 		Type:      t,
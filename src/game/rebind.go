@@ -0,0 +1,140 @@
+package game
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/go-gl/mathgl/mgl32"
+
+	"breakout/src/controls"
+)
+
+type rebindEntry struct {
+	player int
+	action controls.Action
+}
+
+// rebindScreen lists every binding, then a defaults and a back entry.
+type rebindScreen struct {
+	entries   []rebindEntry
+	row       int
+	capturing bool
+	message   string
+}
+
+func (g *Game) openRebind() {
+	entries := make([]rebindEntry, 0)
+	for _, action := range controls.Actions() {
+		if !action.PerPlayer() {
+			entries = append(entries, rebindEntry{0, action})
+		}
+	}
+
+	for player := 0; player < controls.Players; player++ {
+		for _, action := range controls.Actions() {
+			if action.PerPlayer() {
+				entries = append(entries, rebindEntry{player, action})
+			}
+		}
+	}
+
+	g.rebind = rebindScreen{entries: entries}
+	g.State = StateControls
+}
+
+func (g *Game) closeRebind() {
+	g.saveSettings()
+	g.State = StateMenu
+}
+
+func (g *Game) processRebind() {
+	screen := &g.rebind
+
+	if screen.capturing {
+		if key, ok := g.Controls.CapturedKey(); ok {
+			entry := screen.entries[screen.row]
+			screen.message = ""
+			if err := g.Controls.Bindings().Rebind(entry.player, entry.action, key); err != nil {
+				screen.message = fmt.Sprintf("Cannot bind %s: %v", controls.DisplayName(key), err)
+			}
+			screen.capturing = false
+		}
+
+		return
+	}
+
+	rows := len(screen.entries) + 2
+
+	switch {
+	case g.Controls.Pressed(0, controls.MenuUp):
+		screen.row = (screen.row + rows - 1) % rows
+	case g.Controls.Pressed(0, controls.MenuDown):
+		screen.row = (screen.row + 1) % rows
+	case g.Controls.Pressed(0, controls.Pause):
+		g.closeRebind()
+	case g.Controls.Pressed(0, controls.Confirm):
+		switch screen.row {
+		case len(screen.entries):
+			g.Controls.SetBindings(controls.DefaultBindings())
+		case len(screen.entries) + 1:
+			g.closeRebind()
+		default:
+			screen.capturing = true
+		}
+	}
+}
+
+func (g *Game) renderRebind() {
+	screen := &g.rebind
+	white, yellow := mgl32.Vec3{1, 1, 1}, mgl32.Vec3{1, 1, 0}
+
+	g.renderCentered("Controls", 20, 1, &white)
+
+	labels := make([]string, 0, len(screen.entries)+2)
+	for i, entry := range screen.entries {
+		keys := "-"
+		if screen.capturing && i == screen.row {
+			keys = "press a key..."
+		} else if bound := g.Controls.Bindings().Keys(entry.player, entry.action); len(bound) > 0 {
+			names := make([]string, 0, len(bound))
+			for _, key := range bound {
+				names = append(names, controls.DisplayName(key))
+			}
+			keys = strings.Join(names, ", ")
+		}
+
+		name := strings.ReplaceAll(entry.action.String(), "_", " ")
+		if entry.action.PerPlayer() {
+			name = fmt.Sprintf("P%d %s", entry.player+1, name)
+		}
+
+		labels = append(labels, fmt.Sprintf("%-16s %s", name, keys))
+	}
+	labels = append(labels, "Reset to defaults", "Back")
+
+	for i, label := range labels {
+		color := &white
+		if i == screen.row {
+			color = &yellow
+		}
+
+		g.Text.RenderText(label, 200, 60+float32(i)*28, 0.75, color)
+	}
+
+	hint := fmt.Sprintf("%s/%s select, %s rebind, %s back", g.keyLabel(controls.MenuUp), g.keyLabel(controls.MenuDown), g.keyLabel(controls.Confirm), g.keyLabel(controls.Pause))
+	g.renderCentered(hint, float32(g.Height)-30, 0.75, &white)
+
+	if screen.message != "" {
+		g.renderCentered(screen.message, float32(g.Height)-55, 0.75, &yellow)
+	}
+}
+
+// keyLabel names the first key bound to a shared action for on-screen hints.
+func (g *Game) keyLabel(action controls.Action) string {
+	keys := g.Controls.Bindings().Keys(0, action)
+	if len(keys) == 0 {
+		return "(unbound)"
+	}
+
+	return controls.DisplayName(keys[0])
+}
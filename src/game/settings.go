@@ -0,0 +1,171 @@
+package game
+
+import (
+	"log"
+	"strconv"
+
+	"github.com/go-gl/mathgl/mgl32"
+
+	"breakout/src/display"
+	"breakout/src/render"
+	"breakout/src/settings"
+	"breakout/src/ui"
+)
+
+type settingKind int
+
+const (
+	settingChoice settingKind = iota
+	settingToggle
+	settingSlider
+)
+
+// settingsRow is one setting on the screen. The choices can depend on the
+// other settings, the video modes on the monitor for example.
+type settingsRow struct {
+	label   string
+	key     string
+	kind    settingKind
+	choices func(s *settings.Settings) []string
+}
+
+var settingsRows = []settingsRow{
+	{"Resolution", "resolution", settingChoice, fixed("800x600", "1024x768", "1280x720", "1280x960", "1600x900", "1920x1080")},
+	{"Window mode", "window-mode", settingChoice, fixed("windowed", "borderless", "fullscreen")},
+	{"Monitor", "monitor", settingChoice, monitorChoices},
+	{"Video mode", "video-mode", settingChoice, videoModeChoices},
+	{"VSync", "vsync", settingToggle, nil},
+	{"FPS cap", "fps-cap", settingChoice, fixed("0", "30", "60", "120", "144", "240")},
+	{"MSAA", "msaa", settingChoice, fixed("0", "2", "4", "8", "16")},
+	{"Integer scaling", "integer-scaling", settingToggle, nil},
+	{"Master volume", "master-volume", settingSlider, nil},
+	{"Music volume", "music-volume", settingSlider, nil},
+	{"Effects volume", "effects-volume", settingSlider, nil},
+	{"Paddle speed", "paddle-speed", settingChoice, fixed("300", "400", "500", "600", "700", "800")},
+	{"Ball radius", "ball-radius", settingChoice, fixed("7.5", "10", "12.5", "15", "20")},
+	{"Lives", "lives", settingChoice, fixed("1", "2", "3", "4", "5", "7", "9")},
+	{"Particles", "particles", settingChoice, fixed("0", "500", "1000", "2000", "4000", "10000", "20000", "50000")},
+	{"Mouse", "mouse", settingToggle, nil},
+	{"Mouse smoothing", "mouse-smoothing", settingChoice, fixed("0", "0.05", "0.1", "0.2")},
+	{"Mouse max speed", "mouse-max-speed", settingChoice, mouseSpeedChoices},
+}
+
+// settingsScreen edits a copy of the settings that is only applied and
+// written back when saved.
+type settingsScreen struct {
+	draft settings.Settings
+}
+
+func fixed(choices ...string) func(*settings.Settings) []string {
+	return func(*settings.Settings) []string {
+		return choices
+	}
+}
+
+// mouseSpeedChoices leaves out the speeds a paddle this slow cannot reach.
+func mouseSpeedChoices(s *settings.Settings) []string {
+	choices := make([]string, 0, 5)
+	for _, speed := range []float32{750, 1000, 1500, 2000, 3000} {
+		if speed <= s.Gameplay.PlayerVelocity*inputSpeedRange {
+			choices = append(choices, strconv.Itoa(int(speed)))
+		}
+	}
+
+	return choices
+}
+
+func monitorChoices(*settings.Settings) []string {
+	monitors := display.Monitors()
+	choices := make([]string, 0, len(monitors))
+	for i := range monitors {
+		choices = append(choices, strconv.Itoa(i))
+	}
+
+	return choices
+}
+
+func videoModeChoices(s *settings.Settings) []string {
+	choices := []string{settings.VideoMode{}.String()}
+	for _, mode := range display.VideoModes(s.Monitor) {
+		choices = append(choices, mode.String())
+	}
+
+	return choices
+}
+
+func (g *Game) openSettings() {
+	g.Settings.Bindings = g.Controls.Bindings()
+	g.Settings.Mouse = g.Controls.Mouse
+
+	g.options = settingsScreen{draft: *g.Settings}
+	g.State = StateSettings
+}
+
+func (g *Game) buildSettings() *ui.Screen {
+	screen := &g.options
+	items := []ui.Widget{ui.NewTitle("Settings", 1)}
+
+	for _, row := range settingsRows {
+		items = append(items, screen.widget(row))
+	}
+
+	save := ui.NewButton("Save", func() {
+		draft := screen.draft
+		g.ApplySettings(&draft)
+		g.saveSettings()
+		g.State = StateMenu
+	})
+	back := ui.NewButton("Back", func() { g.State = StateMenu })
+
+	grey := render.TextStyle{Color: mgl32.Vec3{0.7, 0.7, 0.7}}
+	note := &ui.Label{Text: "The particle count applies after a restart", Align: render.AlignCenter, Style: &grey}
+	hint := &ui.Label{Text: g.menuHint(), Align: render.AlignCenter}
+
+	items = append(items, ui.HBox(save, back), note, hint)
+
+	s := ui.NewScreen(ui.NewPanel(ui.VBox(items...)))
+	s.OnBack = func() { g.State = StateMenu }
+	s.Theme = compactTheme()
+
+	return s
+}
+
+// widget edits one setting of the draft.
+func (s *settingsScreen) widget(row settingsRow) ui.Widget {
+	get := func() string {
+		value, _ := s.draft.Get(row.key)
+		return value
+	}
+
+	set := func(value string) {
+		if err := s.draft.Set(row.key, value); err != nil {
+			log.Println("failed to change setting:", err)
+		}
+	}
+
+	switch row.kind {
+	case settingToggle:
+		return ui.NewToggle(row.label, func() bool { return get() == "true" }, func(on bool) { set(strconv.FormatBool(on)) })
+	case settingSlider:
+		value := func() float32 {
+			v, _ := strconv.ParseFloat(get(), 32)
+			return float32(v)
+		}
+
+		return ui.NewSlider(row.label, 0, 1, 0.1, value, func(v float32) { set(strconv.FormatFloat(float64(v), 'f', -1, 32)) })
+	}
+
+	choice := ui.NewChoice(row.label, func() []string { return row.choices(&s.draft) }, get, set)
+	if row.key == "monitor" {
+		choice.Format = func(value string) string {
+			monitors := display.Monitors()
+			if i, err := strconv.Atoi(value); err == nil && i < len(monitors) {
+				return value + " " + monitors[i]
+			}
+
+			return value
+		}
+	}
+
+	return choice
+}
@@ -0,0 +1,187 @@
+package game
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+
+	"github.com/go-gl/mathgl/mgl32"
+
+	"breakout/src/spectate"
+)
+
+type BrickState struct {
+	Rect  [4]float32 `json:"rect"`
+	Color string     `json:"color"`
+	Solid bool       `json:"solid,omitempty"`
+}
+
+type PowerUpState struct {
+	Type     string     `json:"type"`
+	Rect     [4]float32 `json:"rect"`
+	Color    string     `json:"color"`
+	Falling  bool       `json:"falling"`
+	Duration float64    `json:"duration,omitempty"`
+}
+
+type Snapshot struct {
+	Type      string         `json:"type"`
+	Tick      uint64         `json:"tick"`
+	Ball      [3]float32     `json:"ball"`
+	Paddles   [][4]float32   `json:"paddles"`
+	Destroyed string         `json:"destroyed"`
+	Moving    [][3]float32   `json:"moving,omitempty"`
+	PowerUps  []PowerUpState `json:"powerups"`
+	Lives     []uint32       `json:"lives"`
+	Score     []int          `json:"score"`
+
+	Width  int          `json:"width,omitempty"`
+	Height int          `json:"height,omitempty"`
+	Level  int          `json:"level"`
+	Bricks []BrickState `json:"bricks,omitempty"`
+}
+
+type EventMessage struct {
+	Type     string     `json:"type"`
+	Event    string     `json:"event"`
+	Tick     uint64     `json:"tick"`
+	Player   int        `json:"player"`
+	Position [2]float32 `json:"position"`
+	Name     string     `json:"name,omitempty"`
+}
+
+// Snapshot captures the dynamic board state. A full snapshot also carries
+// the brick layout the destroyed bitmap refers to.
+func (g *Game) Snapshot(full bool) Snapshot {
+	level := &g.Levels[g.Level]
+
+	s := Snapshot{
+		Type:     "tick",
+		Tick:     g.tick,
+		Ball:     [3]float32{g.ball.Position.X(), g.ball.Position.Y(), g.ball.Radius},
+		Paddles:  make([][4]float32, 0, len(g.players)),
+		PowerUps: make([]PowerUpState, 0, len(g.PowerUps)),
+		Level:    g.Level,
+	}
+
+	for _, p := range g.activePlayers() {
+		s.Paddles = append(s.Paddles, rect(p.Paddle))
+		s.Lives = append(s.Lives, p.Lives)
+		s.Score = append(s.Score, p.Score)
+	}
+
+	bitmap := make([]byte, (len(level.Bricks)+7)/8)
+	for i, brick := range level.Bricks {
+		if brick.Destroyed {
+			bitmap[i/8] |= 1 << (i % 8)
+		}
+
+		if brick.Motion != nil && !full {
+			s.Moving = append(s.Moving, [3]float32{float32(i), brick.Position.X(), brick.Position.Y()})
+		}
+	}
+	s.Destroyed = base64.StdEncoding.EncodeToString(bitmap)
+
+	for i := range g.PowerUps {
+		p := &g.PowerUps[i]
+		s.PowerUps = append(s.PowerUps, PowerUpState{
+			Type:     p.Type,
+			Rect:     rect(&p.Object),
+			Color:    hexColor(p.Color),
+			Falling:  !p.Destroyed,
+			Duration: p.Duration,
+		})
+	}
+
+	if full {
+		s.Type = "full"
+		s.Width = g.Width
+		s.Height = g.Height
+		s.Bricks = make([]BrickState, 0, len(level.Bricks))
+
+		for _, brick := range level.Bricks {
+			s.Bricks = append(s.Bricks, BrickState{
+				Rect:  rect(&brick.Object),
+				Color: hexColor(brick.Color),
+				Solid: brick.IsSolid,
+			})
+		}
+	}
+
+	return s
+}
+
+// layout is what a full snapshot describes beyond the dynamic state, a
+// change sends every spectator a new one.
+type layout struct {
+	level  int
+	bricks int
+	pruned int
+	offset float32
+	mode   Mode
+}
+
+func (g *Game) layout() layout {
+	level := &g.Levels[g.Level]
+
+	return layout{level: g.Level, bricks: len(level.Bricks), pruned: level.pruned, offset: level.offset, mode: g.Mode}
+}
+
+// Spectate streams snapshots and events to the server every tick while
+// anyone is watching.
+func (g *Game) Spectate(server *spectate.Server) {
+	shown := layout{level: -1}
+
+	g.OnEvent(func(e Event) {
+		if !server.HasClients() {
+			return
+		}
+
+		msg, err := json.Marshal(EventMessage{
+			Type:     "event",
+			Event:    e.Type.String(),
+			Tick:     e.Tick,
+			Player:   e.Player,
+			Position: e.Position,
+			Name:     e.Name,
+		})
+		if err == nil {
+			server.Broadcast(msg)
+		}
+	})
+
+	g.OnTick(func() {
+		if !server.HasClients() {
+			return
+		}
+
+		current := g.layout()
+		changed := current != shown
+
+		if changed || server.HasWaiting() {
+			shown = current
+
+			if msg, err := json.Marshal(g.Snapshot(true)); err == nil {
+				server.PublishFull(msg, changed)
+			}
+
+			return
+		}
+
+		if msg, err := json.Marshal(g.Snapshot(false)); err == nil {
+			server.Broadcast(msg)
+		}
+	})
+}
+
+func rect(o *Object) [4]float32 {
+	return [4]float32{o.Position.X(), o.Position.Y(), o.Size.X(), o.Size.Y()}
+}
+
+func hexColor(c mgl32.Vec3) string {
+	clamp := func(v float32) int {
+		return int(mgl32.Clamp(v, 0, 1) * 255)
+	}
+
+	return fmt.Sprintf("#%02x%02x%02x", clamp(c.X()), clamp(c.Y()), clamp(c.Z()))
+}
@@ -1,30 +1,109 @@
 package main
 
 import (
+	"flag"
 	"fmt"
+	"io/fs"
 	"log"
+	"os"
 	"runtime"
+	"time"
 
 	"github.com/go-gl/gl/v3.3-core/gl"
 	"github.com/go-gl/glfw/v3.3/glfw"
 
+	"breakout/resources"
+	"breakout/src/assets"
+	"breakout/src/display"
 	"breakout/src/game"
-	"breakout/src/resource"
+	"breakout/src/netplay"
+	"breakout/src/render"
+	"breakout/src/settings"
+	"breakout/src/spectate"
 )
 
+// The game is simulated and drawn on a canvas of this size and scaled to the
+// window.
 const (
-	ScreenWidth  = 800
-	ScreenHeight = 600
-	WindowTitle  = "Breakout"
+	CanvasWidth  = 800
+	CanvasHeight = 600
 )
 
-var breakout *game.Game
+var (
+	breakout *game.Game
+	options  *settings.Settings
+
+	hostAddress = flag.String("host", "", "host a versus match on the given address, e.g. :7777")
+	joinAddress = flag.String("join", "", "join a versus match at the given address, e.g. 127.0.0.1:7777")
+	matchSeed   = flag.Int64("seed", 0, "versus match seed, random when zero")
+	matchLevel  = flag.Uint("level", 0, "versus match level index")
+	inputDelay  = flag.Uint("delay", 3, "versus match input delay in ticks")
+	spectateOn  = flag.String("spectate", "", "stream game state to spectators on the given address, e.g. 127.0.0.1:8090")
+	playtest    = flag.Bool("playtest", false, "let the autopilot play every level headlessly and print a report")
+	envServer   = flag.Bool("env", false, "serve a headless training environment as line-delimited JSON on stdin/stdout")
+	configFile  = flag.String("settings", settings.DefaultPath(), "settings file")
+	hotReload   = flag.Bool("hot-reload", false, "reload shaders, textures, levels and particle emitters when their files change, in the -overlay directory or ./resources")
+	assetsFile  = flag.String("assets", "", "packed archive to read the resources from instead of the embedded ones")
+	overlayDir  = flag.String("overlay", "", "directory of loose files that replace the packed ones, for mods")
+	packFile    = flag.String("pack", "", "write the resources, or the -overlay directory, into a packed archive and exit")
+	checkFiles  = flag.Bool("check-assets", false, "report the files in the asset manifest that are missing and exit")
+	drawStats   = flag.Bool("draw-stats", false, "log the draw calls and sprites of a frame once a second")
+	overrides   = settings.RegisterFlags(flag.CommandLine)
+)
 
 func main() {
+	flag.Parse()
+
+	var err error
+	options, err = loadSettings()
+	if err != nil {
+		handleFatalError(err)
+	}
+
+	if *packFile != "" {
+		if err := packAssets(); err != nil {
+			handleFatalError(fmt.Errorf("failed to pack assets: %w", err))
+		}
+		return
+	}
+
+	if *checkFiles {
+		if err := checkAssets(); err != nil {
+			handleFatalError(err)
+		}
+		return
+	}
+
+	fsys, err := openAssets()
+	if err != nil {
+		handleFatalError(fmt.Errorf("failed to open assets: %w", err))
+	}
+
+	if *envServer {
+		if err := game.ServeEnvironment(CanvasWidth, CanvasHeight, fsys, os.Stdin, os.Stdout); err != nil {
+			handleFatalError(fmt.Errorf("failed to serve environment: %w", err))
+		}
+		return
+	}
+
+	if *playtest {
+		if err := runPlaytest(fsys); err != nil {
+			handleFatalError(fmt.Errorf("failed to playtest: %w", err))
+		}
+		return
+	}
+
 	runtime.LockOSThread()
-	defer resource.Cleanup()
 
-	breakout = game.NewGame(ScreenWidth, ScreenHeight)
+	breakout = game.NewGame(CanvasWidth, CanvasHeight)
+	breakout.Assets = fsys
+	breakout.ApplySettings(options)
+	breakout.SettingsFile = *configFile
+
+	conn, player, config, err := connectMatch()
+	if err != nil {
+		handleFatalError(fmt.Errorf("failed to connect match: %w", err))
+	}
 
 	window, err := initGLFW()
 	if err != nil {
@@ -43,7 +122,39 @@ func main() {
 	}
 	defer breakout.Cleanup()
 
-	var deltaTime, lastTime float64
+	if err := resize(window.Window); err != nil {
+		handleFatalError(err)
+	}
+	breakout.OnSettingsChanged(window.Apply)
+
+	if *hotReload {
+		if err := breakout.EnableHotReload(overlayRoot()); err != nil {
+			handleFatalError(err)
+		}
+	}
+
+	if conn != nil {
+		err = breakout.StartMatch(conn, player, config)
+		if err != nil {
+			handleFatalError(fmt.Errorf("failed to start match: %w", err))
+		}
+	}
+
+	if *spectateOn != "" {
+		server, err := spectate.NewServer(*spectateOn)
+		if err != nil {
+			handleFatalError(fmt.Errorf("failed to start spectator server: %w", err))
+		}
+		defer server.Close()
+
+		breakout.Spectate(server)
+		log.Printf("Spectator viewer at http://%s/", server.Address())
+	}
+
+	var (
+		deltaTime, lastTime float64
+		statsTime           float64
+	)
 
 	for !window.ShouldClose() {
 		currTime := glfw.GetTime()
@@ -53,22 +164,155 @@ func main() {
 		glfw.PollEvents()
 
 		breakout.ProcessInput(deltaTime)
+		applyCursorMode(window.Window)
 
 		breakout.Update(deltaTime)
+		breakout.PollHotReload(deltaTime)
 
 		gl.ClearColor(0, 0, 0, 1)
 		gl.Clear(gl.COLOR_BUFFER_BIT)
+		render.ResetStats()
 		breakout.Render()
 
+		if *drawStats && currTime-statsTime >= 1 {
+			statsTime = currTime
+			stats := render.CurrentStats()
+			log.Printf("draw calls: %d, sprites: %d", stats.DrawCalls, stats.Sprites)
+		}
+
 		window.SwapBuffers()
+		window.Limit()
+	}
+}
+
+// loadSettings reads the settings file and applies the command line flags
+// on top of it.
+func loadSettings() (*settings.Settings, error) {
+	s, err := settings.Load(*configFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load settings: %w", err)
+	}
+
+	if err := overrides.Apply(s); err != nil {
+		return nil, fmt.Errorf("failed to apply settings flags: %w", err)
+	}
+
+	return s, nil
+}
+
+// openAssets picks the files the game reads: the embedded resources or a
+// packed archive, with the loose files of the overlay directory on top.
+func openAssets() (fs.FS, error) {
+	base := resources.FS
+	if *assetsFile != "" {
+		archive, err := assets.OpenArchive(*assetsFile)
+		if err != nil {
+			return nil, err
+		}
+
+		base = archive
+	}
+
+	if root := overlayRoot(); root != "" {
+		return assets.Overlay(os.DirFS(root), base), nil
+	}
+
+	return base, nil
+}
+
+// overlayRoot is the -overlay directory. Hot reload without one watches the
+// resources directory of a source checkout.
+func overlayRoot() string {
+	if *overlayDir == "" && *hotReload {
+		return "resources"
 	}
+
+	return *overlayDir
+}
+
+// checkAssets validates the manifest against the assets the game would
+// read with the same flags.
+func checkAssets() error {
+	fsys, err := openAssets()
+	if err != nil {
+		return fmt.Errorf("failed to open assets: %w", err)
+	}
+
+	manifest, err := assets.LoadManifest(fsys, game.ManifestFile)
+	if err != nil {
+		return err
+	}
+
+	if err := manifest.Validate(fsys); err != nil {
+		return fmt.Errorf("missing assets:\n%w", err)
+	}
+
+	if err := manifest.MissingOptional(fsys); err != nil {
+		fmt.Printf("warning: missing optional assets:\n%v\n", err)
+		return nil
+	}
+
+	fmt.Println("all assets are present")
+
+	return nil
+}
+
+func packAssets() error {
+	fsys := resources.FS
+	if *overlayDir != "" {
+		fsys = os.DirFS(*overlayDir)
+	}
+
+	file, err := os.Create(*packFile)
+	if err != nil {
+		return fmt.Errorf("failed to create archive: %w", err)
+	}
+
+	if err := assets.Pack(file, fsys); err != nil {
+		file.Close()
+		return err
+	}
+
+	return file.Close()
+}
+
+func connectMatch() (*netplay.Conn, int, netplay.Config, error) {
+	switch {
+	case *hostAddress != "":
+		config := netplay.Config{
+			Seed:  *matchSeed,
+			Level: uint16(*matchLevel),
+			Delay: uint16(*inputDelay),
+		}
+		if config.Seed == 0 {
+			config.Seed = time.Now().UnixNano()
+		}
+
+		log.Println("Waiting for opponent on", *hostAddress)
+
+		conn, err := netplay.Host(*hostAddress, config)
+
+		return conn, 0, config, err
+	case *joinAddress != "":
+		conn, config, err := netplay.Join(*joinAddress)
+
+		return conn, 1, config, err
+	}
+
+	return nil, 0, netplay.Config{}, nil
+}
+
+func runPlaytest(fsys fs.FS) error {
+	return game.PlaytestLevels(fsys, CanvasWidth, CanvasHeight, *matchSeed, 0, func(report game.PlaytestReport) {
+		fmt.Println(report)
+	})
 }
 
 func handleFatalError(err error) {
 	log.Fatal(err)
 }
 
-func initGLFW() (*glfw.Window, error) {
+func initGLFW() (*display.Window, error) {
 	err := glfw.Init()
 	if err != nil {
 		panic(err)
@@ -78,22 +322,41 @@ func initGLFW() (*glfw.Window, error) {
 	glfw.WindowHint(glfw.ContextVersionMinor, 3)
 	glfw.WindowHint(glfw.OpenGLProfile, glfw.OpenGLCoreProfile)
 	glfw.WindowHint(glfw.OpenGLForwardCompatible, glfw.True)
+	glfw.WindowHint(glfw.ScaleToMonitor, glfw.True)
 
-	window, err := glfw.CreateWindow(ScreenWidth, ScreenHeight, WindowTitle, nil, nil)
+	window, err := display.NewWindow(options)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create a window: %w", err)
+		return nil, err
 	}
 
-	window.MakeContextCurrent()
-
 	window.SetKeyCallback(keyCallback)
+	window.SetCursorPosCallback(cursorPosCallback)
+	window.SetMouseButtonCallback(mouseButtonCallback)
+	window.SetSizeCallback(sizeCallback)
 	window.SetFramebufferSizeCallback(framebufferSizeCallback)
+	glfw.SetJoystickCallback(breakout.Controls.OnJoystick)
+	breakout.Controls.DetectGamepads()
 
 	return window, nil
 }
 
-func framebufferSizeCallback(_ *glfw.Window, width, height int) {
-	gl.Viewport(0, 0, int32(width), int32(height))
+// resize fits the game into the window. The framebuffer is larger than the
+// window on HiDPI displays, both sizes are needed to map the cursor.
+func resize(window *glfw.Window) error {
+	framebufferWidth, framebufferHeight := window.GetFramebufferSize()
+	width, height := window.GetSize()
+
+	if err := breakout.Resize(framebufferWidth, framebufferHeight, width, height); err != nil {
+		return fmt.Errorf("failed to resize: %w", err)
+	}
+
+	return nil
+}
+
+func framebufferSizeCallback(window *glfw.Window, _, _ int) {
+	if err := resize(window); err != nil {
+		log.Println(err)
+	}
 }
 
 func keyCallback(window *glfw.Window, key glfw.Key, scancode int, action glfw.Action, mods glfw.ModifierKey) {
@@ -101,13 +364,31 @@ func keyCallback(window *glfw.Window, key glfw.Key, scancode int, action glfw.Ac
 		window.SetShouldClose(true)
 	}
 
-	if key >= 0 && key < 1024 {
-		if action == glfw.Press {
-			breakout.Keys[key] = true
-		} else if action == glfw.Release {
-			breakout.Keys[key] = false
-			breakout.KeysProcessed[key] = false
-		}
+	breakout.Controls.OnKey(key, action)
+}
+
+func cursorPosCallback(_ *glfw.Window, x, y float64) {
+	breakout.Controls.OnCursor(x, y)
+}
+
+func mouseButtonCallback(_ *glfw.Window, button glfw.MouseButton, action glfw.Action, _ glfw.ModifierKey) {
+	breakout.Controls.OnMouseButton(button, action)
+}
+
+func sizeCallback(window *glfw.Window, _, _ int) {
+	if err := resize(window); err != nil {
+		log.Println(err)
+	}
+}
+
+func applyCursorMode(window *glfw.Window) {
+	mode := glfw.CursorNormal
+	if breakout.Controls.CursorCaptured() {
+		mode = glfw.CursorDisabled
+	}
+
+	if window.GetInputMode(glfw.CursorMode) != mode {
+		window.SetInputMode(glfw.CursorMode, mode)
 	}
 }
 
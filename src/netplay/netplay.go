@@ -0,0 +1,239 @@
+package netplay
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+const (
+	Version = 2
+
+	magic            = "BRKT"
+	handshakeTimeout = 10 * time.Second
+	messageSize      = 17
+	queueSize        = 1024
+)
+
+type MessageKind uint8
+
+const (
+	MessageInput MessageKind = iota + 1
+	MessageHash
+	MessageBye
+)
+
+type Message struct {
+	Kind  MessageKind
+	Tick  uint64
+	Value uint64
+}
+
+type Config struct {
+	Seed  int64
+	Level uint16
+	Delay uint16
+}
+
+var ErrVersionMismatch = errors.New("protocol version mismatch")
+
+type Conn struct {
+	conn     net.Conn
+	messages chan Message
+
+	mu  sync.Mutex
+	err error
+}
+
+// Listener waits for the opponent of a hosted match. Listening on port 0
+// picks a free port, Addr tells which.
+type Listener struct {
+	listener net.Listener
+}
+
+func Listen(address string) (*Listener, error) {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen: %w", err)
+	}
+
+	return &Listener{listener: listener}, nil
+}
+
+func (l *Listener) Addr() net.Addr {
+	return l.listener.Addr()
+}
+
+// Accept waits for the opponent and sends them the match config.
+func (l *Listener) Accept(config Config) (*Conn, error) {
+	conn, err := l.listener.Accept()
+	if err != nil {
+		return nil, fmt.Errorf("failed to accept connection: %w", err)
+	}
+
+	if err = exchangeHello(conn); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to exchange hello: %w", err)
+	}
+
+	if err = binary.Write(conn, binary.BigEndian, config); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to send config: %w", err)
+	}
+
+	return newConn(conn), nil
+}
+
+func (l *Listener) Close() error {
+	return l.listener.Close()
+}
+
+// Host listens on address until one opponent joins.
+func Host(address string, config Config) (*Conn, error) {
+	l, err := Listen(address)
+	if err != nil {
+		return nil, err
+	}
+	defer l.Close()
+
+	return l.Accept(config)
+}
+
+func Join(address string) (*Conn, Config, error) {
+	var config Config
+
+	conn, err := net.DialTimeout("tcp", address, handshakeTimeout)
+	if err != nil {
+		return nil, config, fmt.Errorf("failed to dial: %w", err)
+	}
+
+	if err = exchangeHello(conn); err != nil {
+		conn.Close()
+		return nil, config, fmt.Errorf("failed to exchange hello: %w", err)
+	}
+
+	if err = binary.Read(conn, binary.BigEndian, &config); err != nil {
+		conn.Close()
+		return nil, config, fmt.Errorf("failed to read config: %w", err)
+	}
+
+	return newConn(conn), config, nil
+}
+
+func exchangeHello(conn net.Conn) error {
+	if err := conn.SetDeadline(time.Now().Add(handshakeTimeout)); err != nil {
+		return fmt.Errorf("failed to set deadline: %w", err)
+	}
+	defer conn.SetDeadline(time.Time{})
+
+	hello := make([]byte, len(magic)+2)
+	copy(hello, magic)
+	binary.BigEndian.PutUint16(hello[len(magic):], Version)
+
+	if _, err := conn.Write(hello); err != nil {
+		return fmt.Errorf("failed to send hello: %w", err)
+	}
+
+	remote := make([]byte, len(hello))
+	if _, err := io.ReadFull(conn, remote); err != nil {
+		return fmt.Errorf("failed to read hello: %w", err)
+	}
+
+	if string(remote[:len(magic)]) != magic {
+		return fmt.Errorf("unexpected magic %q", remote[:len(magic)])
+	}
+
+	if version := binary.BigEndian.Uint16(remote[len(magic):]); version != Version {
+		return fmt.Errorf("%w: local %d, remote %d", ErrVersionMismatch, Version, version)
+	}
+
+	return nil
+}
+
+func newConn(conn net.Conn) *Conn {
+	c := &Conn{
+		conn:     conn,
+		messages: make(chan Message, queueSize),
+	}
+
+	go c.readLoop()
+
+	return c
+}
+
+func (c *Conn) readLoop() {
+	defer close(c.messages)
+
+	buf := make([]byte, messageSize)
+
+	for {
+		if _, err := io.ReadFull(c.conn, buf); err != nil {
+			c.setErr(fmt.Errorf("failed to read message: %w", err))
+			return
+		}
+
+		m := Message{
+			Kind:  MessageKind(buf[0]),
+			Tick:  binary.BigEndian.Uint64(buf[1:9]),
+			Value: binary.BigEndian.Uint64(buf[9:17]),
+		}
+
+		if m.Kind == MessageBye {
+			c.setErr(io.EOF)
+			return
+		}
+
+		c.messages <- m
+	}
+}
+
+func (c *Conn) Messages() <-chan Message {
+	return c.messages
+}
+
+func (c *Conn) SendInput(tick uint64, input uint16) error {
+	return c.send(Message{Kind: MessageInput, Tick: tick, Value: uint64(input)})
+}
+
+func (c *Conn) SendHash(tick, hash uint64) error {
+	return c.send(Message{Kind: MessageHash, Tick: tick, Value: hash})
+}
+
+func (c *Conn) send(m Message) error {
+	buf := make([]byte, messageSize)
+	buf[0] = byte(m.Kind)
+	binary.BigEndian.PutUint64(buf[1:9], m.Tick)
+	binary.BigEndian.PutUint64(buf[9:17], m.Value)
+
+	if _, err := c.conn.Write(buf); err != nil {
+		return fmt.Errorf("failed to send message: %w", err)
+	}
+
+	return nil
+}
+
+func (c *Conn) Err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.err
+}
+
+func (c *Conn) setErr(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.err == nil {
+		c.err = err
+	}
+}
+
+func (c *Conn) Close() error {
+	_ = c.send(Message{Kind: MessageBye})
+
+	return c.conn.Close()
+}
@@ -0,0 +1,142 @@
+package render
+
+import (
+	"image"
+	"image/draw"
+	"log"
+
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/golang/freetype/truetype"
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
+
+	"breakout/src/texture"
+)
+
+const (
+	// Glyphs are rasterized at sdfSize pixels whatever the font size, the
+	// distance field keeps them sharp when drawn smaller or larger.
+	sdfSize = 48
+
+	// sdfSpread is how many pixels the field reaches past the outline. It
+	// bounds outlines, glows and shadow offsets.
+	sdfSpread = 8
+)
+
+// glyphCache rasterizes glyphs into distance fields the first time they are
+// drawn. A rune comes from the first font in the chain that has it, or the
+// missing glyph box of the first font when none does. Sizes are in pixels
+// of the font size, unit converts from the rasterized size.
+type glyphCache struct {
+	fonts  []*truetype.Font
+	faces  []font.Face
+	atlas  *texture.DynamicAtlas
+	glyphs map[rune]*character
+	unit   float32
+}
+
+type character struct {
+	// region is nil for glyphs with nothing to draw, like spaces.
+	region *texture.Region
+
+	// bearing is the top left corner of the quad relative to the pen on
+	// the baseline, size its width and height.
+	bearing mgl32.Vec2
+	size    mgl32.Vec2
+	advance float32
+	face    int
+}
+
+func newGlyphCache(fonts []*truetype.Font, size int) *glyphCache {
+	c := &glyphCache{
+		fonts:  fonts,
+		atlas:  texture.NewDynamicAtlas(glyphPageSize),
+		glyphs: make(map[rune]*character),
+		unit:   float32(size) / sdfSize,
+	}
+
+	for _, f := range fonts {
+		c.faces = append(c.faces, truetype.NewFace(f, &truetype.Options{
+			Size:    sdfSize,
+			DPI:     dpi,
+			Hinting: font.HintingNone,
+		}))
+	}
+
+	return c
+}
+
+// metrics returns the ascent and line height of the first font.
+func (c *glyphCache) metrics() (ascent, lineHeight float32) {
+	m := c.faces[0].Metrics()
+
+	return fixedToFloat(m.Ascent) * c.unit, fixedToFloat(m.Height) * c.unit
+}
+
+func (c *glyphCache) glyph(r rune) *character {
+	if char, ok := c.glyphs[r]; ok {
+		return char
+	}
+
+	char := c.rasterize(r)
+	c.glyphs[r] = char
+
+	return char
+}
+
+func (c *glyphCache) rasterize(r rune) *character {
+	index := 0
+	for i, f := range c.fonts {
+		if f.Index(r) != 0 {
+			index = i
+			break
+		}
+	}
+
+	face := c.faces[index]
+	dr, mask, maskp, advance, _ := face.Glyph(fixed.Point26_6{}, r)
+	char := &character{
+		bearing: mgl32.Vec2{float32(dr.Min.X - sdfSpread), float32(dr.Min.Y - sdfSpread)}.Mul(c.unit),
+		size:    mgl32.Vec2{float32(dr.Dx() + 2*sdfSpread), float32(dr.Dy() + 2*sdfSpread)}.Mul(c.unit),
+		advance: fixedToFloat(advance) * c.unit,
+		face:    index,
+	}
+
+	if dr.Empty() {
+		return char
+	}
+
+	img := image.NewAlpha(image.Rect(0, 0, dr.Dx(), dr.Dy()))
+	draw.Draw(img, img.Bounds(), mask, maskp, draw.Src)
+
+	region, err := c.atlas.Add(distanceField(img, sdfSpread))
+	if err != nil {
+		log.Printf("warning: failed to cache glyph %q: %v", r, err)
+		return char
+	}
+
+	char.region = region
+
+	return char
+}
+
+// kern is the adjustment between two glyphs of the same font.
+func (c *glyphCache) kern(prev, next rune, prevChar, nextChar *character) float32 {
+	if prevChar.face != nextChar.face {
+		return 0
+	}
+
+	return fixedToFloat(c.faces[nextChar.face].Kern(prev, next)) * c.unit
+}
+
+func (c *glyphCache) delete() {
+	c.atlas.Delete()
+
+	for _, face := range c.faces {
+		face.Close()
+	}
+}
+
+func fixedToFloat(v fixed.Int26_6) float32 {
+	return float32(v) / 64
+}
@@ -14,21 +14,23 @@ type PostProcessor struct {
 	s *shader.Shader
 	t *texture.Texture2D
 
-	Width  int32
-	Height int32
+	Width   int32
+	Height  int32
+	Samples int32
 
 	Confuse bool
 	Chaos   bool
 	Shake   bool
 
-	msfbo uint32
-	fbo   uint32
-	rbo   uint32
-	vao   uint32
+	msfbo    uint32
+	fbo      uint32
+	rbo      uint32
+	vao      uint32
+	viewport [4]int32
 }
 
-func NewPostProcessor(s *shader.Shader, width int, height int) (*PostProcessor, error) {
-	p := &PostProcessor{s: s, t: texture.NewTexture2D(), Width: int32(width), Height: int32(height)}
+func NewPostProcessor(s *shader.Shader, width int, height int, samples int) (*PostProcessor, error) {
+	p := &PostProcessor{s: s, t: texture.NewTexture2D(), Width: int32(width), Height: int32(height), Samples: int32(samples)}
 	err := p.init()
 	if err != nil {
 		return nil, fmt.Errorf("failed to init post processor: %w", err)
@@ -44,7 +46,7 @@ func (p *PostProcessor) init() error {
 	}
 
 	p.initRenderData()
-	p.initUniforms()
+	p.InitUniforms()
 
 	return nil
 }
@@ -56,7 +58,7 @@ func (p *PostProcessor) initFrameBuffers() error {
 
 	gl.BindFramebuffer(gl.FRAMEBUFFER, p.msfbo)
 	gl.BindRenderbuffer(gl.RENDERBUFFER, p.rbo)
-	gl.RenderbufferStorageMultisample(gl.RENDERBUFFER, 4, gl.RGB, p.Width, p.Height)
+	gl.RenderbufferStorageMultisample(gl.RENDERBUFFER, p.Samples, gl.RGB, p.Width, p.Height)
 	gl.FramebufferRenderbuffer(gl.FRAMEBUFFER, gl.COLOR_ATTACHMENT0, gl.RENDERBUFFER, p.rbo)
 
 	if gl.CheckFramebufferStatus(gl.FRAMEBUFFER) != gl.FRAMEBUFFER_COMPLETE {
@@ -76,6 +78,47 @@ func (p *PostProcessor) initFrameBuffers() error {
 	return nil
 }
 
+// Resize recreates the render targets at a new size in pixels. The scene is
+// still drawn with the projection it was set up with, only the resolution
+// of the targets changes.
+func (p *PostProcessor) Resize(width, height int) error {
+	if int32(width) == p.Width && int32(height) == p.Height {
+		return nil
+	}
+
+	p.deleteFrameBuffers()
+	p.Width, p.Height = int32(width), int32(height)
+
+	if err := p.initFrameBuffers(); err != nil {
+		return fmt.Errorf("failed to recreate framebuffers: %w", err)
+	}
+
+	return nil
+}
+
+// SetSamples recreates the multisampled render target with a new sample
+// count.
+func (p *PostProcessor) SetSamples(samples int) error {
+	if int32(samples) == p.Samples {
+		return nil
+	}
+
+	p.deleteFrameBuffers()
+	p.Samples = int32(samples)
+
+	if err := p.initFrameBuffers(); err != nil {
+		return fmt.Errorf("failed to recreate framebuffers: %w", err)
+	}
+
+	return nil
+}
+
+func (p *PostProcessor) deleteFrameBuffers() {
+	gl.DeleteFramebuffers(1, &p.msfbo)
+	gl.DeleteFramebuffers(1, &p.fbo)
+	gl.DeleteRenderbuffers(1, &p.rbo)
+}
+
 func (p *PostProcessor) initRenderData() {
 	var (
 		vbo      uint32
@@ -110,7 +153,9 @@ func (p *PostProcessor) initRenderData() {
 	gl.BindVertexArray(0)
 }
 
-func (p *PostProcessor) initUniforms() {
+// InitUniforms sets the uniforms that never change, it has to run again
+// after the shader is reloaded.
+func (p *PostProcessor) InitUniforms() {
 	p.s.SetInteger("scene", 0, true)
 
 	var offset float32 = 1.0 / 300
@@ -148,8 +193,13 @@ func (p *PostProcessor) initUniforms() {
 	})
 }
 
+// BeginRender redirects drawing into the render targets until EndRender,
+// which restores the viewport that was active before.
 func (p *PostProcessor) BeginRender() {
+	gl.GetIntegerv(gl.VIEWPORT, &p.viewport[0])
+
 	gl.BindFramebuffer(gl.FRAMEBUFFER, p.msfbo)
+	gl.Viewport(0, 0, p.Width, p.Height)
 	gl.ClearColor(0, 0, 0, 1)
 	gl.Clear(gl.COLOR_BUFFER_BIT)
 }
@@ -170,6 +220,7 @@ func (p *PostProcessor) EndRender() {
 		gl.NEAREST,
 	)
 	gl.BindFramebuffer(gl.FRAMEBUFFER, 0)
+	gl.Viewport(p.viewport[0], p.viewport[1], p.viewport[2], p.viewport[3])
 }
 
 func (p *PostProcessor) Render(time float64) {
@@ -183,6 +234,7 @@ func (p *PostProcessor) Render(time float64) {
 	p.t.Bind()
 	gl.BindVertexArray(p.vao)
 	gl.DrawArrays(gl.TRIANGLES, 0, 6)
+	stats.DrawCalls++
 	gl.BindVertexArray(0)
 }
 
@@ -0,0 +1,86 @@
+package render
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"sort"
+)
+
+// distanceOffset is a neighbour of a pixel and how far away it is.
+type distanceOffset struct {
+	dx, dy   int
+	distance float64
+}
+
+// distanceField turns a glyph mask into a signed distance field, spread
+// pixels larger on every side. Alpha is 0.5 on the outline, rising to 1
+// spread pixels inside the glyph and falling to 0 spread pixels outside, so
+// the edge can be found again at any scale.
+func distanceField(mask image.Image, spread int) *image.NRGBA {
+	var (
+		bounds = mask.Bounds()
+		w, h   = bounds.Dx(), bounds.Dy()
+		inside = make([]bool, w*h)
+		field  = image.NewNRGBA(image.Rect(0, 0, w+2*spread, h+2*spread))
+	)
+
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			_, _, _, a := mask.At(bounds.Min.X+x, bounds.Min.Y+y).RGBA()
+			inside[y*w+x] = a >= 0x8000
+		}
+	}
+
+	isInside := func(x, y int) bool {
+		return x >= 0 && y >= 0 && x < w && y < h && inside[y*w+x]
+	}
+
+	offsets := distanceOffsets(spread)
+
+	for y := 0; y < field.Rect.Dy(); y++ {
+		for x := 0; x < field.Rect.Dx(); x++ {
+			mx, my := x-spread, y-spread
+			in := isInside(mx, my)
+			distance := float64(spread)
+
+			// The offsets are sorted, the first pixel on the other side of
+			// the outline is the nearest.
+			for _, o := range offsets {
+				if isInside(mx+o.dx, my+o.dy) != in {
+					distance = o.distance - 0.5
+					break
+				}
+			}
+
+			if !in {
+				distance = -distance
+			}
+
+			value := 0.5 + distance/float64(2*spread)
+			value = math.Max(0, math.Min(1, value))
+			field.SetNRGBA(x, y, color.NRGBA{255, 255, 255, uint8(value*255 + 0.5)})
+		}
+	}
+
+	return field
+}
+
+func distanceOffsets(spread int) []distanceOffset {
+	var offsets []distanceOffset
+
+	for dy := -spread; dy <= spread; dy++ {
+		for dx := -spread; dx <= spread; dx++ {
+			distance := math.Hypot(float64(dx), float64(dy))
+			if (dx != 0 || dy != 0) && distance <= float64(spread) {
+				offsets = append(offsets, distanceOffset{dx, dy, distance})
+			}
+		}
+	}
+
+	sort.Slice(offsets, func(i, j int) bool {
+		return offsets[i].distance < offsets[j].distance
+	})
+
+	return offsets
+}
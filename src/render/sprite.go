@@ -1,6 +1,8 @@
 package render
 
 import (
+	"math"
+	"sort"
 	"unsafe"
 
 	"github.com/go-gl/gl/v3.3-core/gl"
@@ -10,9 +12,38 @@ import (
 	"breakout/src/texture"
 )
 
+const (
+	// batchSize is how many sprites fit in the vertex buffer, a longer run
+	// of sprites on one texture is drawn in more than one call.
+	batchSize = 4096
+
+	// A vertex is the position, texture coordinates and color.
+	vertexFloats = 7
+)
+
+// sprite is one queued Draw.
+type sprite struct {
+	layer    int
+	region   *texture.Region
+	position mgl32.Vec2
+	size     mgl32.Vec2
+	rotate   float32
+	color    mgl32.Vec3
+}
+
+// SpriteRenderer draws batches of sprites. Sprites drawn between Begin and
+// End are sorted by layer, transformed on the CPU into one streaming vertex
+// buffer and drawn with a call per run of sprites on the same texture.
 type SpriteRenderer struct {
-	s       *shader.Shader
-	quadVAO uint32
+	s *shader.Shader
+
+	vao uint32
+	vbo uint32
+	ebo uint32
+
+	batching bool
+	sprites  []sprite
+	vertices []float32
 }
 
 func NewSpriteRenderer(s *shader.Shader) *SpriteRenderer {
@@ -23,67 +54,146 @@ func NewSpriteRenderer(s *shader.Shader) *SpriteRenderer {
 }
 
 func (s *SpriteRenderer) Cleanup() {
-	gl.DeleteVertexArrays(1, &s.quadVAO)
+	gl.DeleteVertexArrays(1, &s.vao)
+	gl.DeleteBuffers(1, &s.vbo)
+	gl.DeleteBuffers(1, &s.ebo)
+}
+
+// Begin starts a batch. Nothing is drawn until End.
+func (s *SpriteRenderer) Begin() {
+	s.batching = true
+	s.sprites = s.sprites[:0]
+}
+
+// Draw queues a sprite. Lower layers are drawn first, sprites on the same
+// layer in the order they were queued.
+func (s *SpriteRenderer) Draw(layer int, region *texture.Region, position, size mgl32.Vec2, rotate float32, color mgl32.Vec3) {
+	s.sprites = append(s.sprites, sprite{layer, region, position, size, rotate, color})
 }
 
+// End draws the batch.
+func (s *SpriteRenderer) End() {
+	s.batching = false
+
+	sort.SliceStable(s.sprites, func(i, j int) bool {
+		return s.sprites[i].layer < s.sprites[j].layer
+	})
+
+	s.s.Use()
+	gl.ActiveTexture(gl.TEXTURE0)
+	gl.BindVertexArray(s.vao)
+	gl.BindBuffer(gl.ARRAY_BUFFER, s.vbo)
+
+	s.vertices = s.vertices[:0]
+	var current *texture.Texture2D
+
+	for i := range s.sprites {
+		sp := &s.sprites[i]
+
+		if sp.region.Texture != current || len(s.vertices) == batchSize*4*vertexFloats {
+			s.flush(current)
+			current = sp.region.Texture
+		}
+
+		s.appendQuad(sp)
+	}
+
+	s.flush(current)
+
+	gl.BindBuffer(gl.ARRAY_BUFFER, 0)
+	gl.BindVertexArray(0)
+}
+
+// DrawSprite draws one sprite on layer zero. Inside Begin and End it is
+// queued with the rest of the batch.
 func (s *SpriteRenderer) DrawSprite(
-	t *texture.Texture2D,
+	region *texture.Region,
 	position, size *mgl32.Vec2,
 	rotate float32,
 	color *mgl32.Vec3,
 ) {
-	s.s.Use()
-	model := mgl32.Translate3D(position.X(), position.Y(), 0)
+	if s.batching {
+		s.Draw(0, region, *position, *size, rotate, *color)
+		return
+	}
+
+	s.Begin()
+	s.Draw(0, region, *position, *size, rotate, *color)
+	s.End()
+}
 
-	model = model.Mul4(mgl32.Translate3D(0.5*size.X(), 0.5*size.Y(), 0))
-	model = model.Mul4(mgl32.HomogRotate3DZ(mgl32.DegToRad(rotate)))
-	model = model.Mul4(mgl32.Translate3D(-0.5*size.X(), -0.5*size.Y(), 0))
+func (s *SpriteRenderer) flush(t *texture.Texture2D) {
+	if len(s.vertices) == 0 {
+		return
+	}
 
-	model = model.Mul4(mgl32.Scale3D(size.X(), size.Y(), 1))
+	t.Bind()
 
-	s.s.SetMatrix4("model", &model, false)
+	// Orphaning the buffer lets the driver hand out fresh memory instead of
+	// waiting for the previous draw to finish with it.
+	size := len(s.vertices) * int(unsafe.Sizeof(s.vertices[0]))
+	gl.BufferData(gl.ARRAY_BUFFER, batchSize*4*vertexFloats*int(unsafe.Sizeof(s.vertices[0])), nil, gl.STREAM_DRAW)
+	gl.BufferSubData(gl.ARRAY_BUFFER, 0, size, gl.Ptr(s.vertices))
 
-	s.s.SetVector3fv("spriteColor", color, false)
+	quads := len(s.vertices) / (4 * vertexFloats)
+	gl.DrawElements(gl.TRIANGLES, int32(quads*6), gl.UNSIGNED_SHORT, nil)
 
-	gl.ActiveTexture(gl.TEXTURE0)
-	t.Bind()
+	stats.DrawCalls++
+	stats.Sprites += quads
 
-	gl.BindVertexArray(s.quadVAO)
-	gl.DrawArrays(gl.TRIANGLES, 0, 6)
-	gl.BindVertexArray(0)
+	s.vertices = s.vertices[:0]
+}
+
+// appendQuad adds the corners of a sprite, rotated around its centre.
+func (s *SpriteRenderer) appendQuad(sp *sprite) {
+	half := sp.size.Mul(0.5)
+	center := sp.position.Add(half)
+	sin, cos := math.Sincos(float64(mgl32.DegToRad(sp.rotate)))
+	uv := sp.region.UV
+
+	for _, corner := range [4][2]float32{{0, 0}, {1, 0}, {1, 1}, {0, 1}} {
+		x := corner[0]*sp.size.X() - half.X()
+		y := corner[1]*sp.size.Y() - half.Y()
+
+		s.vertices = append(s.vertices,
+			center.X()+x*float32(cos)-y*float32(sin),
+			center.Y()+x*float32(sin)+y*float32(cos),
+			uv[0]+(uv[2]-uv[0])*corner[0],
+			uv[1]+(uv[3]-uv[1])*corner[1],
+			sp.color.X(), sp.color.Y(), sp.color.Z(),
+		)
+	}
 }
 
 func (s *SpriteRenderer) initRenderData() {
 	var (
-		vertices = []float32{
-			0, 1, 0, 1,
-			1, 0, 1, 0,
-			0, 0, 0, 0,
-
-			0, 1, 0, 1,
-			1, 1, 1, 1,
-			1, 0, 1, 0,
-		}
-
-		vbo   uint32
 		fType float32
-		fSize = int(unsafe.Sizeof(fType))
+		fSize = int32(unsafe.Sizeof(fType))
 	)
 
-	gl.GenVertexArrays(1, &s.quadVAO)
-	gl.GenBuffers(1, &vbo)
+	// Every quad is two triangles over its four corners.
+	indices := make([]uint16, 0, batchSize*6)
+	for i := uint16(0); i < batchSize; i++ {
+		indices = append(indices, 4*i, 4*i+1, 4*i+2, 4*i, 4*i+2, 4*i+3)
+	}
 
-	gl.BindBuffer(gl.ARRAY_BUFFER, vbo)
-	gl.BufferData(
-		gl.ARRAY_BUFFER,
-		len(vertices)*fSize,
-		gl.Ptr(&vertices[0]),
-		gl.STATIC_DRAW,
-	)
+	gl.GenVertexArrays(1, &s.vao)
+	gl.GenBuffers(1, &s.vbo)
+	gl.GenBuffers(1, &s.ebo)
+
+	gl.BindVertexArray(s.vao)
+
+	gl.BindBuffer(gl.ARRAY_BUFFER, s.vbo)
+	gl.BufferData(gl.ARRAY_BUFFER, batchSize*4*vertexFloats*int(fSize), nil, gl.STREAM_DRAW)
+
+	gl.BindBuffer(gl.ELEMENT_ARRAY_BUFFER, s.ebo)
+	gl.BufferData(gl.ELEMENT_ARRAY_BUFFER, len(indices)*2, gl.Ptr(indices), gl.STATIC_DRAW)
 
-	gl.BindVertexArray(s.quadVAO)
 	gl.EnableVertexAttribArray(0)
-	gl.VertexAttribPointer(0, 4, gl.FLOAT, false, int32(4*fSize), nil)
-	gl.BindBuffer(gl.ARRAY_BUFFER, 0)
+	gl.VertexAttribPointer(0, 4, gl.FLOAT, false, vertexFloats*fSize, nil)
+	gl.EnableVertexAttribArray(1)
+	gl.VertexAttribPointerWithOffset(1, 3, gl.FLOAT, false, vertexFloats*fSize, uintptr(4*fSize))
+
 	gl.BindVertexArray(0)
+	gl.BindBuffer(gl.ARRAY_BUFFER, 0)
 }
@@ -0,0 +1,24 @@
+package render
+
+// Stats counts what was drawn since the last ResetStats, to see how well
+// the renderers batch.
+type Stats struct {
+	DrawCalls int
+	Sprites   int
+}
+
+var stats Stats
+
+func CurrentStats() Stats {
+	return stats
+}
+
+func ResetStats() {
+	stats = Stats{}
+}
+
+// CountDraw records a draw call made outside the renderers of this package.
+func CountDraw(sprites int) {
+	stats.DrawCalls++
+	stats.Sprites += sprites
+}
@@ -2,63 +2,103 @@ package render
 
 import (
 	"fmt"
-	"image"
-	"image/draw"
-	"os"
+	"io/fs"
+	"strings"
+	"unicode"
 	"unsafe"
 
 	"github.com/go-gl/gl/v3.3-core/gl"
 	"github.com/go-gl/mathgl/mgl32"
-	"github.com/golang/freetype"
 	"github.com/golang/freetype/truetype"
-	"golang.org/x/image/font"
-	"golang.org/x/image/math/fixed"
 
 	"breakout/src/resource"
 	"breakout/src/shader"
-	"breakout/src/types"
+	"breakout/src/texture"
 )
 
 const (
-	vShaderFileName = "resources/shaders/text2d.vert"
-	fShaderFileName = "resources/shaders/text2d.frag"
-	shaderName      = "text"
-	dpi             = 72
-	baselineXOffset = 0
+	shaderName    = "text"
+	dpi           = 72
+	glyphPageSize = 1024
 )
 
+// Align places lines of text relative to the x they are drawn at.
+type Align int
+
+const (
+	AlignLeft Align = iota
+	AlignCenter
+	AlignRight
+)
+
+// TextStyle is how text is drawn. Outline, Shadow and Glow are in pixels of
+// the text at scale 1 and grow with it. Together they should stay within
+// what the distance field reaches, a sixth of the font size.
+type TextStyle struct {
+	Color mgl32.Vec3
+
+	Outline      float32
+	OutlineColor mgl32.Vec3
+
+	Shadow      mgl32.Vec2
+	ShadowColor mgl32.Vec4
+
+	Glow      float32
+	GlowColor mgl32.Vec4
+}
+
 type TextRenderer struct {
-	chars map[rune]*character
-	s     *shader.Shader
+	glyphs *glyphCache
+	s      *shader.Shader
+	handle *resource.Handle
+	fsys   fs.FS
 
 	width  int
 	height int
 
-	vao uint32
-	vbo uint32
+	ascent     float32
+	lineHeight float32
+
+	vao      uint32
+	vbo      uint32
+	vertices []float32
 }
 
-func NewTextRenderer(width, height int) (*TextRenderer, error) {
-	err := resource.LoadShader(vShaderFileName, fShaderFileName, "", shaderName)
+// NewTextRenderer draws with the "text" shader, which has to be loaded into
+// resources already.
+func NewTextRenderer(resources *resource.Manager, width, height int) (*TextRenderer, error) {
+	handle, err := resources.Acquire(resource.KindShader, shaderName)
 	if err != nil {
-		return nil, fmt.Errorf("failed to load shader: %w", err)
+		return nil, fmt.Errorf("failed to acquire shader: %w", err)
 	}
 
-	s := resource.GetShader(shaderName)
-	projection := mgl32.Ortho2D(0, float32(width), float32(height), 0)
-	s.SetMatrix4("projection", &projection, true)
-	s.SetInteger("text", 0, false)
+	s, err := resources.Shader(shaderName)
+	if err != nil {
+		return nil, err
+	}
 
 	r := &TextRenderer{
-		chars: make(map[rune]*character, 0),
-		s:     s,
+		s:      s,
+		handle: handle,
+		fsys:   resources.FS(),
+		width:  width,
+		height: height,
 	}
 
+	r.InitUniforms()
 	r.initBuffers()
 
 	return r, nil
 }
 
+// InitUniforms sets the projection and sampler, it has to run again after the
+// shader is reloaded.
+func (r *TextRenderer) InitUniforms() {
+	projection := mgl32.Ortho2D(0, float32(r.width), float32(r.height), 0)
+	r.s.SetMatrix4("projection", &projection, true)
+	r.s.SetInteger("text", 0, false)
+}
+
 func (r *TextRenderer) initBuffers() {
 	var (
 		fType float32
@@ -69,158 +109,215 @@ func (r *TextRenderer) initBuffers() {
 	gl.GenBuffers(1, &r.vbo)
 	gl.BindVertexArray(r.vao)
 	gl.BindBuffer(gl.ARRAY_BUFFER, r.vbo)
-	gl.BufferData(gl.ARRAY_BUFFER, fSize*6*4, nil, gl.DYNAMIC_DRAW)
 	gl.EnableVertexAttribArray(0)
 	gl.VertexAttribPointer(0, 4, gl.FLOAT, false, int32(4*fSize), nil)
 	gl.BindBuffer(gl.ARRAY_BUFFER, 0)
 	gl.BindVertexArray(0)
 }
 
-func (r *TextRenderer) Load(fontPath string, fontSize int) error {
-	r.chars = make(map[rune]*character, 0)
-
-	ttf, err := loadFont(fontPath)
-	if err != nil {
-		return fmt.Errorf("failed to load font: %w", err)
-	}
-
-	fg, bg := image.White, image.Transparent
+// Load replaces the font. Glyphs the first font lacks come from the
+// fallbacks, in order.
+func (r *TextRenderer) Load(fontPath string, fontSize int, fallbacks ...string) error {
+	var fonts []*truetype.Font
 
-	c := freetype.NewContext()
-	c.SetDPI(dpi)
-	c.SetFont(ttf)
-	c.SetFontSize(float64(fontSize))
-	c.SetSrc(fg)
-	c.SetHinting(font.HintingNone)
-
-	for i := rune(0); i < 128; i++ {
-		char, err := newCharacter(ttf, c, bg, fontSize, i)
+	for _, path := range append([]string{fontPath}, fallbacks...) {
+		ttf, err := loadFont(r.fsys, path)
 		if err != nil {
-			return fmt.Errorf("failed to create character: %w", err)
+			return fmt.Errorf("failed to load font %s: %w", path, err)
 		}
 
-		r.chars[i] = char
+		fonts = append(fonts, ttf)
+	}
+
+	r.deleteGlyphs()
+	r.glyphs = newGlyphCache(fonts, fontSize)
+	r.ascent, r.lineHeight = r.glyphs.metrics()
+
+	// Most text is ASCII, building its distance fields now keeps the first
+	// frames that show it from stalling.
+	for c := rune(' '); c <= '~'; c++ {
+		r.glyphs.glyph(c)
 	}
 
 	return nil
 }
 
+// RenderText draws text with its top left corner at x and y.
 func (r *TextRenderer) RenderText(text string, x, y, scale float32, color *mgl32.Vec3) {
+	r.RenderTextAligned(text, x, y, scale, color, AlignLeft)
+}
+
+// RenderTextAligned draws text below y, every line aligned on x by align.
+func (r *TextRenderer) RenderTextAligned(text string, x, y, scale float32, color *mgl32.Vec3, align Align) {
+	r.RenderTextStyled(text, x, y, scale, &TextStyle{Color: *color}, align)
+}
+
+// RenderTextStyled draws text like RenderTextAligned with an outline, shadow
+// and glow. Glyphs on the same atlas page go out in one draw call.
+func (r *TextRenderer) RenderTextStyled(text string, x, y, scale float32, style *TextStyle, align Align) {
 	r.s.Use()
-	r.s.SetVector3fv("textColor", color, false)
+	r.setStyle(style)
 	gl.ActiveTexture(gl.TEXTURE0)
 	gl.BindVertexArray(r.vao)
+	gl.BindBuffer(gl.ARRAY_BUFFER, r.vbo)
 
-	for _, c := range text {
-		char := r.chars[c]
-
-		xpos := x + float32(char.bearing.X())*scale
-		ypos := y
-		//ypos := y + float32(char.size.Y()-char.bearing.Y())*scale
-
-		w := float32(char.size.X()) * scale
-		h := float32(char.size.Y()) * scale
-
-		vertices := [6][4]float32{
-			{xpos, ypos + h, 0, 1},
-			{xpos + w, ypos, 1, 0},
-			{xpos, ypos, 0, 0},
+	r.vertices = r.vertices[:0]
+	var page *texture.Texture2D
 
-			{xpos, ypos + h, 0, 1},
-			{xpos + w, ypos + h, 1, 1},
-			{xpos + w, ypos, 1, 0},
+	for i, line := range strings.Split(text, "\n") {
+		penX := x
+		switch align {
+		case AlignCenter:
+			penX -= r.lineWidth(line) * scale / 2
+		case AlignRight:
+			penX -= r.lineWidth(line) * scale
 		}
 
-		gl.BindTexture(gl.TEXTURE_2D, char.textureID)
+		baseline := y + (r.ascent+float32(i)*r.lineHeight)*scale
+
+		r.layout(line, func(char *character, offset float32) {
+			if char.region == nil {
+				return
+			}
+
+			if char.region.Texture != page {
+				r.flush(page)
+				page = char.region.Texture
+			}
+
+			xpos := penX + (offset+char.bearing.X())*scale
+			ypos := baseline + char.bearing.Y()*scale
+			w := char.size.X() * scale
+			h := char.size.Y() * scale
+			uv := char.region.UV
+
+			r.vertices = append(r.vertices,
+				xpos, ypos+h, uv[0], uv[3],
+				xpos+w, ypos, uv[2], uv[1],
+				xpos, ypos, uv[0], uv[1],
+
+				xpos, ypos+h, uv[0], uv[3],
+				xpos+w, ypos+h, uv[2], uv[3],
+				xpos+w, ypos, uv[2], uv[1],
+			)
+		})
+	}
 
-		gl.BindBuffer(gl.ARRAY_BUFFER, r.vbo)
-		gl.BufferSubData(gl.ARRAY_BUFFER, 0, int(unsafe.Sizeof(vertices)), gl.Ptr(&vertices[0][0]))
-		gl.BindBuffer(gl.ARRAY_BUFFER, 0)
+	r.flush(page)
 
-		gl.DrawArrays(gl.TRIANGLES, 0, 6)
+	gl.BindBuffer(gl.ARRAY_BUFFER, 0)
+	gl.BindVertexArray(0)
+}
 
-		x += float32(char.advance) * scale
+// Measure is the size of the box RenderText fills with text.
+func (r *TextRenderer) Measure(text string, scale float32) mgl32.Vec2 {
+	var (
+		lines = strings.Split(text, "\n")
+		width float32
+	)
+
+	for _, line := range lines {
+		if w := r.lineWidth(line); w > width {
+			width = w
+		}
 	}
 
-	gl.BindVertexArray(0)
-	gl.BindTexture(gl.TEXTURE_2D, 0)
+	return mgl32.Vec2{width * scale, float32(len(lines)) * r.lineHeight * scale}
 }
 
-func (r *TextRenderer) Cleanup() {
-	for char := range r.chars {
-		gl.DeleteTextures(1, &r.chars[char].textureID)
-	}
+// LineHeight is the distance between the baselines of two lines.
+func (r *TextRenderer) LineHeight(scale float32) float32 {
+	return r.lineHeight * scale
 }
 
-func loadFont(fontPath string) (*truetype.Font, error) {
-	bytes, err := os.ReadFile(fontPath)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read font file: %w", err)
-	}
+// setStyle converts the pixel sizes of the style into distances in the
+// field and texture coordinates for the shader.
+func (r *TextRenderer) setStyle(style *TextStyle) {
+	var (
+		field  = 1 / (r.glyphs.unit * 2 * sdfSpread)
+		shadow = style.Shadow.Mul(1 / (r.glyphs.unit * glyphPageSize))
+	)
 
-	ttf, err := truetype.Parse(bytes)
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse font file: %w", err)
-	}
+	r.s.SetVector3fv("textColor", &style.Color, false)
+	r.s.SetFloat("outlineWidth", style.Outline*field, false)
+	r.s.SetVector3fv("outlineColor", &style.OutlineColor, false)
+	r.s.SetVector2fv("shadowOffset", &shadow, false)
+	r.s.SetVector4fv("shadowColor", &style.ShadowColor, false)
+	r.s.SetFloat("glowWidth", style.Glow*field, false)
+	r.s.SetVector4fv("glowColor", &style.GlowColor, false)
+}
 
-	return ttf, nil
+func (r *TextRenderer) lineWidth(line string) float32 {
+	var width float32
+
+	r.layout(line, func(char *character, offset float32) {
+		width = offset + char.advance
+	})
+
+	return width
 }
 
-type character struct {
-	textureID uint32
-	size      types.IVec2
-	bearing   types.IVec2
-	advance   int
+// layout walks the glyphs of a line with the distance of each from the
+// start of the line, kerning included. Control characters are skipped.
+func (r *TextRenderer) layout(line string, glyph func(char *character, offset float32)) {
+	var (
+		offset   float32
+		prev     rune
+		prevChar *character
+	)
+
+	for _, c := range line {
+		if unicode.IsControl(c) {
+			continue
+		}
+
+		char := r.glyphs.glyph(c)
+		if prevChar != nil {
+			offset += r.glyphs.kern(prev, c, prevChar, char)
+		}
+
+		glyph(char, offset)
+
+		offset += char.advance
+		prev, prevChar = c, char
+	}
 }
 
-func newCharacter(ttf *truetype.Font, c *freetype.Context, bg *image.Uniform, fontSize int, r rune) (*character, error) {
-	glyphBounds := ttf.Bounds(fixed.Int26_6(fontSize))
-	glyphWidth := int(glyphBounds.Max.X - glyphBounds.Min.X)
-	glyphHeight := int(glyphBounds.Max.Y - glyphBounds.Min.Y)
+func (r *TextRenderer) flush(page *texture.Texture2D) {
+	if len(r.vertices) == 0 {
+		return
+	}
 
-	img := image.NewRGBA(image.Rect(0, 0, glyphWidth, glyphHeight))
-	draw.Draw(img, img.Bounds(), bg, image.Point{}, draw.Src)
+	page.Bind()
+	gl.BufferData(gl.ARRAY_BUFFER, len(r.vertices)*int(unsafe.Sizeof(r.vertices[0])), gl.Ptr(r.vertices), gl.STREAM_DRAW)
+	gl.DrawArrays(gl.TRIANGLES, 0, int32(len(r.vertices)/4))
+	stats.DrawCalls++
 
-	c.SetClip(img.Bounds())
-	c.SetDst(img)
+	r.vertices = r.vertices[:0]
+}
 
-	baselineX, baselineY := baselineXOffset, int(c.PointToFixed(float64(fontSize))>>6)
+func (r *TextRenderer) Cleanup() {
+	r.deleteGlyphs()
+	r.handle.Release()
+}
 
-	pt := freetype.Pt(baselineX, baselineY)
+func (r *TextRenderer) deleteGlyphs() {
+	if r.glyphs != nil {
+		r.glyphs.delete()
+		r.glyphs = nil
+	}
+}
 
-	_, err := c.DrawString(string(r), pt)
+func loadFont(fsys fs.FS, fontPath string) (*truetype.Font, error) {
+	bytes, err := fs.ReadFile(fsys, fontPath)
 	if err != nil {
-		return nil, fmt.Errorf("failed to draw character: %w", err)
+		return nil, fmt.Errorf("failed to read font file: %w", err)
 	}
 
-	return &character{
-		textureID: newCharacterTexture(img),
-		size:      types.IVec2{img.Bounds().Dx(), img.Bounds().Dy()},
-		bearing:   types.IVec2{-baselineX, baselineY},
-		advance:   int(ttf.HMetric(fixed.Int26_6(fontSize), ttf.Index(r)).AdvanceWidth),
-	}, nil
-}
-
-func newCharacterTexture(img *image.RGBA) uint32 {
-	var id uint32
-	gl.GenTextures(1, &id)
-	gl.BindTexture(gl.TEXTURE_2D, id)
-	gl.TexImage2D(
-		gl.TEXTURE_2D,
-		0,
-		gl.RGBA,
-		int32(img.Bounds().Dx()),
-		int32(img.Bounds().Dy()),
-		0,
-		gl.RGBA,
-		gl.UNSIGNED_BYTE,
-		gl.Ptr(img.Pix),
-	)
-
-	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_S, gl.CLAMP_TO_EDGE)
-	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_T, gl.CLAMP_TO_EDGE)
-	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MIN_FILTER, gl.LINEAR)
-	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MAG_FILTER, gl.LINEAR)
+	ttf, err := truetype.Parse(bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse font file: %w", err)
+	}
 
-	return id
+	return ttf, nil
 }
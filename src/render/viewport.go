@@ -0,0 +1,42 @@
+package render
+
+import (
+	"math"
+
+	"github.com/go-gl/gl/v3.3-core/gl"
+)
+
+// Viewport is the part of the framebuffer the virtual canvas is drawn to, in
+// pixels with the origin at the bottom left like gl.Viewport.
+type Viewport struct {
+	X      int32
+	Y      int32
+	Width  int32
+	Height int32
+}
+
+// FitViewport scales a canvas to the largest size that fits the framebuffer
+// without changing its aspect ratio and centers it, leaving bars at the top
+// and bottom (letterbox) or at the sides (pillarbox). Integer scaling rounds
+// the scale down to a whole number once the framebuffer is large enough,
+// so every canvas pixel covers the same number of screen pixels.
+func FitViewport(canvasWidth, canvasHeight, width, height int, integer bool) Viewport {
+	scale := math.Min(float64(width)/float64(canvasWidth), float64(height)/float64(canvasHeight))
+	if integer && scale >= 1 {
+		scale = math.Floor(scale)
+	}
+
+	w := int32(math.Round(float64(canvasWidth) * scale))
+	h := int32(math.Round(float64(canvasHeight) * scale))
+
+	return Viewport{
+		X:      (int32(width) - w) / 2,
+		Y:      (int32(height) - h) / 2,
+		Width:  w,
+		Height: h,
+	}
+}
+
+func (v Viewport) Apply() {
+	gl.Viewport(v.X, v.Y, v.Width, v.Height)
+}
@@ -0,0 +1,88 @@
+package resource
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+
+	"breakout/src/texture"
+)
+
+// AtlasPageSize is the size of an atlas page, every GL 3.3 driver in use
+// supports textures at least this large.
+const AtlasPageSize = 2048
+
+// AtlasImage is one image to pack, it is loaded under its own name.
+type AtlasImage struct {
+	Name     string
+	FileName string
+	Options  TextureOptions
+}
+
+// LoadAtlas packs images into shared pages instead of a texture each, so
+// sprites drawn after each other do not switch textures. Images are grouped
+// by filter, a page has one. Missing files load the fallback like
+// LoadTexture does.
+func (m *Manager) LoadAtlas(images []AtlasImage) ([]*Handle, error) {
+	var (
+		handles  []*Handle
+		builders = map[bool]*texture.AtlasBuilder{}
+		pending  = map[string]*entry{}
+	)
+
+	for _, img := range images {
+		if h, ok, err := m.acquire(KindTexture, img.Name, img.FileName); ok || err != nil {
+			if err != nil {
+				return nil, err
+			}
+
+			handles = append(handles, h)
+
+			continue
+		}
+
+		e := &entry{source: img.FileName, files: []string{img.FileName}, options: img.Options}
+
+		pixels, err := decodeImage(m.fsys, img.FileName, img.Options)
+		if errors.Is(err, fs.ErrNotExist) {
+			log.Printf("warning: using the fallback for %s texture: %v", img.Name, err)
+
+			e.region = m.fallbackRegion()
+			e.fallback = true
+			handles = append(handles, m.add(KindTexture, img.Name, e))
+
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to load %s texture: %w", img.Name, err)
+		}
+
+		b, ok := builders[img.Options.Nearest]
+		if !ok {
+			b = texture.NewAtlasBuilder(AtlasPageSize, img.Options.Nearest)
+			builders[img.Options.Nearest] = b
+		}
+
+		b.Add(img.Name, pixels)
+		pending[img.Name] = e
+	}
+
+	for _, b := range builders {
+		atlas, err := b.Build()
+		if err != nil {
+			return nil, fmt.Errorf("failed to build atlas: %w", err)
+		}
+
+		m.atlases = append(m.atlases, atlas)
+
+		for name, region := range atlas.Regions {
+			e := pending[name]
+			e.region = region
+			e.packed = true
+			handles = append(handles, m.add(KindTexture, name, e))
+		}
+	}
+
+	return handles, nil
+}
@@ -0,0 +1,90 @@
+package resource
+
+import (
+	"fmt"
+
+	"github.com/go-gl/gl/v3.3-core/gl"
+
+	"breakout/src/shader"
+	"breakout/src/texture"
+)
+
+const (
+	checkerSize = 16
+	checkerCell = 8
+)
+
+// The fallback shader takes the sprite vertex layout and draws flat magenta,
+// which stands out without breaking the frame.
+const (
+	fallbackVertexSource = `#version 330 core
+layout (location = 0) in vec4 vertex;
+
+uniform mat4 projection;
+
+void main() {
+    gl_Position = projection * vec4(vertex.xy, 0.0, 1.0);
+}
+`
+
+	fallbackFragmentSource = `#version 330 core
+out vec4 color;
+
+void main() {
+    color = vec4(1.0, 0.0, 1.0, 1.0);
+}
+`
+)
+
+// FallbackShader is used in place of shaders whose files are missing.
+func (m *Manager) FallbackShader() (*shader.Shader, error) {
+	if m.fallbackShader != nil {
+		return m.fallbackShader, nil
+	}
+
+	s := shader.NewShader()
+	if err := s.Compile(fallbackVertexSource, fallbackFragmentSource, ""); err != nil {
+		return nil, fmt.Errorf("failed to compile fallback shader: %w", err)
+	}
+
+	m.fallbackShader = s
+
+	return s, nil
+}
+
+// FallbackTexture is a magenta and black checkerboard used in place of
+// textures whose files are missing.
+func (m *Manager) FallbackTexture() *texture.Texture2D {
+	if m.fallbackTexture != nil {
+		return m.fallbackTexture
+	}
+
+	pixels := make([]byte, 0, checkerSize*checkerSize*4)
+	for y := 0; y < checkerSize; y++ {
+		for x := 0; x < checkerSize; x++ {
+			if (x/checkerCell+y/checkerCell)%2 == 0 {
+				pixels = append(pixels, 255, 0, 255, 255)
+			} else {
+				pixels = append(pixels, 0, 0, 0, 255)
+			}
+		}
+	}
+
+	t := texture.NewTexture2D()
+	t.InternalFormat = gl.RGBA
+	t.ImageFormat = gl.RGBA
+	t.FilterMin = gl.NEAREST
+	t.FilterMax = gl.NEAREST
+	t.Generate(checkerSize, checkerSize, gl.Ptr(pixels))
+
+	m.fallbackTexture = t
+
+	return t
+}
+
+// fallbackRegion covers a copy of the fallback texture, so a reload can
+// replace the texture inside it without touching the other users.
+func (m *Manager) fallbackRegion() *texture.Region {
+	t := *m.FallbackTexture()
+	return texture.NewRegion(&t)
+}
@@ -1,69 +1,420 @@
 package resource
 
 import (
+	"errors"
 	"fmt"
-	"io"
-	"os"
+	"image"
+	"image/draw"
+	_ "image/jpeg"
+	_ "image/png"
+	"io/fs"
+	"log"
+	"path"
 
 	"github.com/go-gl/gl/v3.3-core/gl"
-	"github.com/nicholasblaskey/stbi"
 
 	"breakout/src/shader"
 	"breakout/src/texture"
 )
 
-var (
-	shaders  = make(map[string]*shader.Shader)
-	textures = make(map[string]*texture.Texture2D)
+type Kind int
+
+const (
+	KindShader Kind = iota
+	KindTexture
 )
 
-func LoadShader(vShaderFileName, fShaderFileName, gShaderFileName, name string) error {
-	var err error
-	if _, ok := shaders[name]; ok {
-		return fmt.Errorf("shader %s is already loaded", name)
+func (k Kind) String() string {
+	if k == KindShader {
+		return "shader"
+	}
+
+	return "texture"
+}
+
+// NotFoundError is returned when a resource is asked for by a name that is
+// not loaded.
+type NotFoundError struct {
+	Kind Kind
+	Name string
+}
+
+func (e *NotFoundError) Error() string {
+	return fmt.Sprintf("%s %s is not loaded", e.Kind, e.Name)
+}
+
+// TextureOptions is how an image is uploaded. Nearest filtering keeps
+// pixel art sharp when it is scaled.
+type TextureOptions struct {
+	Alpha   bool
+	Nearest bool
+}
+
+type entry struct {
+	source   string
+	files    []string
+	options  TextureOptions
+	refs     int
+	fallback bool
+
+	// packed regions are part of an atlas page, which the manager frees
+	// with the atlas.
+	packed bool
+
+	shader *shader.Shader
+	region *texture.Region
+}
+
+// Manager owns the shaders and textures loaded into the OpenGL context.
+// Resources are reference counted by name: loading a name again hands out
+// another Handle to the same resource, and it is freed once every handle
+// is released or it is unloaded by name. File names are paths in the
+// manager's file system.
+type Manager struct {
+	fsys    fs.FS
+	entries [2]map[string]*entry
+
+	fallbackShader  *shader.Shader
+	fallbackTexture *texture.Texture2D
+	atlases         []*texture.Atlas
+}
+
+func NewManager(fsys fs.FS) *Manager {
+	return &Manager{
+		fsys: fsys,
+		entries: [2]map[string]*entry{
+			KindShader:  make(map[string]*entry),
+			KindTexture: make(map[string]*entry),
+		},
+	}
+}
+
+// FS is the file system the resources are read from.
+func (m *Manager) FS() fs.FS {
+	return m.fsys
+}
+
+// Handle is one reference to a loaded resource. It keeps the entry it
+// refers to, so a handle left over from before an unload never releases a
+// resource loaded again under the same name.
+type Handle struct {
+	m     *Manager
+	kind  Kind
+	name  string
+	entry *entry
+}
+
+func (h *Handle) Name() string {
+	return h.name
+}
+
+// Release drops the reference. Releasing a handle twice, or after the
+// resource was unloaded, does nothing.
+func (h *Handle) Release() {
+	if h.m == nil {
+		return
+	}
+
+	h.m.release(h)
+	h.m = nil
+}
+
+// LoadShader compiles a shader from its source files. A missing file loads
+// the fallback shader in its place with a warning, a shader that fails to
+// compile is an error.
+func (m *Manager) LoadShader(name, vShaderFileName, fShaderFileName, gShaderFileName string) (*Handle, error) {
+	source := vShaderFileName + "|" + fShaderFileName + "|" + gShaderFileName
+	if h, ok, err := m.acquire(KindShader, name, source); ok || err != nil {
+		return h, err
 	}
 
-	shaders[name], err = loadShaderFromFile(vShaderFileName, fShaderFileName, gShaderFileName)
+	e := &entry{source: source, files: []string{vShaderFileName, fShaderFileName, gShaderFileName}}
+
+	s, err := loadShaderFromFile(m.fsys, vShaderFileName, fShaderFileName, gShaderFileName)
+	if errors.Is(err, fs.ErrNotExist) {
+		log.Printf("warning: using the fallback for %s shader: %v", name, err)
+
+		fallback, err := m.FallbackShader()
+		if err != nil {
+			return nil, fmt.Errorf("failed to create fallback shader: %w", err)
+		}
+
+		// Every entry gets its own copy, a reload replaces the program
+		// inside it without touching the other users of the fallback.
+		e.shader = &shader.Shader{ID: fallback.ID}
+		e.fallback = true
+
+		return m.add(KindShader, name, e), nil
+	}
 	if err != nil {
-		return fmt.Errorf("faled to load shader from file: %w", err)
+		return nil, fmt.Errorf("failed to load shader from file: %w", err)
 	}
 
-	return nil
+	e.shader = s
+
+	return m.add(KindShader, name, e), nil
 }
 
-func GetShader(name string) *shader.Shader {
-	return shaders[name]
+// LoadTexture uploads an image file into a texture of its own. A missing
+// file loads the fallback checkerboard in its place with a warning.
+func (m *Manager) LoadTexture(name, fileName string, options TextureOptions) (*Handle, error) {
+	if h, ok, err := m.acquire(KindTexture, name, fileName); ok || err != nil {
+		return h, err
+	}
+
+	e := &entry{source: fileName, files: []string{fileName}, options: options}
+
+	if _, err := fs.Stat(m.fsys, fileName); errors.Is(err, fs.ErrNotExist) {
+		log.Printf("warning: using the fallback for %s texture: %v", name, err)
+
+		e.region = m.fallbackRegion()
+		e.fallback = true
+
+		return m.add(KindTexture, name, e), nil
+	}
+
+	t, err := loadTextureFromFile(m.fsys, fileName, options)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load texture from file: %w", err)
+	}
+
+	e.region = texture.NewRegion(t)
+
+	return m.add(KindTexture, name, e), nil
+}
+
+// Acquire hands out another reference to a resource that is loaded already.
+func (m *Manager) Acquire(kind Kind, name string) (*Handle, error) {
+	e, ok := m.entries[kind][name]
+	if !ok {
+		return nil, &NotFoundError{Kind: kind, Name: name}
+	}
+
+	e.refs++
+
+	return &Handle{m: m, kind: kind, name: name, entry: e}, nil
+}
+
+func (m *Manager) Shader(name string) (*shader.Shader, error) {
+	e, ok := m.entries[KindShader][name]
+	if !ok {
+		return nil, &NotFoundError{Kind: KindShader, Name: name}
+	}
+
+	return e.shader, nil
 }
 
-func LoadTexture(fileName string, alpha bool, name string) error {
-	var err error
-	if _, ok := textures[name]; ok {
-		return fmt.Errorf("texture %s is already loaded", name)
+// Region returns where a texture is drawn from, its own texture or its
+// place on an atlas page.
+func (m *Manager) Region(name string) (*texture.Region, error) {
+	e, ok := m.entries[KindTexture][name]
+	if !ok {
+		return nil, &NotFoundError{Kind: KindTexture, Name: name}
 	}
 
-	textures[name], err = loadTextureFromFile(fileName, alpha)
+	return e.region, nil
+}
+
+// RegionOrFallback returns the region, or the fallback with a warning when
+// it is not loaded. A nil manager, as used by headless games, returns nil.
+func (m *Manager) RegionOrFallback(name string) *texture.Region {
+	if m == nil {
+		return nil
+	}
+
+	r, err := m.Region(name)
 	if err != nil {
-		return fmt.Errorf("failed to load texture from file: %w", err)
+		log.Printf("warning: %v, drawing the fallback", err)
+
+		// Remember the fallback under the name so the warning is logged once.
+		r = m.fallbackRegion()
+		m.add(KindTexture, name, &entry{region: r, fallback: true})
+	}
+
+	return r
+}
+
+// Reload loads again every resource that was loaded from the file, in place,
+// so the shaders and textures handed out so far see the new contents. A
+// shader that fails to compile keeps its old program and the error carries
+// the GL log. It returns the names of the reloaded resources.
+func (m *Manager) Reload(fileName string) ([]string, error) {
+	var (
+		names []string
+		errs  []error
+	)
+
+	fileName = path.Clean(fileName)
+
+	for kind := range m.entries {
+		for name, e := range m.entries[kind] {
+			if !e.uses(fileName) {
+				continue
+			}
+
+			if err := e.reload(m.fsys); err != nil {
+				errs = append(errs, fmt.Errorf("failed to reload %s %s: %w", Kind(kind), name, err))
+				continue
+			}
+
+			names = append(names, name)
+		}
+	}
+
+	return names, errors.Join(errs...)
+}
+
+// Unload frees a resource right away, whatever handles are still out.
+func (m *Manager) Unload(kind Kind, name string) error {
+	e, ok := m.entries[kind][name]
+	if !ok {
+		return &NotFoundError{Kind: kind, Name: name}
 	}
 
+	delete(m.entries[kind], name)
+	e.free()
+
 	return nil
 }
 
-func GetTexture(name string) *texture.Texture2D {
-	return textures[name]
+// Cleanup frees every resource and the fallbacks.
+func (m *Manager) Cleanup() {
+	for kind := range m.entries {
+		for name, e := range m.entries[kind] {
+			delete(m.entries[kind], name)
+			e.free()
+		}
+	}
+
+	if m.fallbackShader != nil {
+		gl.DeleteProgram(m.fallbackShader.ID)
+		m.fallbackShader = nil
+	}
+
+	if m.fallbackTexture != nil {
+		m.fallbackTexture.Delete()
+		m.fallbackTexture = nil
+	}
+
+	for _, a := range m.atlases {
+		a.Delete()
+	}
+	m.atlases = nil
+}
+
+// acquire hands out another reference when the name is already loaded from
+// the same source.
+func (m *Manager) acquire(kind Kind, name, source string) (*Handle, bool, error) {
+	e, ok := m.entries[kind][name]
+	if !ok {
+		return nil, false, nil
+	}
+
+	if e.source != source {
+		return nil, false, fmt.Errorf("%s %s is already loaded from %s", kind, name, e.source)
+	}
+
+	e.refs++
+
+	return &Handle{m: m, kind: kind, name: name, entry: e}, true, nil
+}
+
+func (m *Manager) add(kind Kind, name string, e *entry) *Handle {
+	e.refs = 1
+	m.entries[kind][name] = e
+
+	return &Handle{m: m, kind: kind, name: name, entry: e}
+}
+
+func (m *Manager) release(h *Handle) {
+	e, ok := m.entries[h.kind][h.name]
+	if !ok || e != h.entry {
+		return
+	}
+
+	e.refs--
+	if e.refs <= 0 {
+		delete(m.entries[h.kind], h.name)
+		e.free()
+	}
 }
 
-func Cleanup() {
-	for name := range shaders {
-		gl.DeleteProgram(shaders[name].ID)
+func (e *entry) free() {
+	if e.fallback || e.packed {
+		return
 	}
 
-	for name := range textures {
-		gl.DeleteTextures(1, &textures[name].ID)
+	if e.shader != nil {
+		gl.DeleteProgram(e.shader.ID)
+	}
+
+	if e.region != nil {
+		e.region.Texture.Delete()
+	}
+}
+
+func (e *entry) uses(fileName string) bool {
+	for _, f := range e.files {
+		if f != "" && path.Clean(f) == fileName {
+			return true
+		}
 	}
+
+	return false
 }
 
-func loadShaderFromFile(vShaderFileName, fShaderFileName, gShaderFileName string) (*shader.Shader, error) {
+func (e *entry) reload(fsys fs.FS) error {
+	if e.shader != nil {
+		s, err := loadShaderFromFile(fsys, e.files[0], e.files[1], e.files[2])
+		if err != nil {
+			return err
+		}
+
+		if !e.fallback {
+			gl.DeleteProgram(e.shader.ID)
+		}
+
+		e.shader.ID = s.ID
+		e.fallback = false
+
+		return nil
+	}
+
+	// The fallback is shared and a packed image cannot grow on its page,
+	// both get a texture of their own when their size no longer fits.
+	if e.packed && !e.fallback {
+		img, err := decodeImage(fsys, e.files[0], e.options)
+		if err != nil {
+			return err
+		}
+
+		if img.Rect.Size() == e.region.Rect.Size() {
+			return e.region.Update(img)
+		}
+	}
+
+	if e.fallback || e.packed {
+		t, err := loadTextureFromFile(fsys, e.files[0], e.options)
+		if err != nil {
+			return err
+		}
+
+		*e.region = *texture.NewRegion(t)
+		e.fallback, e.packed = false, false
+
+		return nil
+	}
+
+	if err := uploadImage(fsys, e.region.Texture, e.files[0], e.options); err != nil {
+		return err
+	}
+
+	*e.region = *texture.NewRegion(e.region.Texture)
+
+	return nil
+}
+
+func loadShaderFromFile(fsys fs.FS, vShaderFileName, fShaderFileName, gShaderFileName string) (*shader.Shader, error) {
 	if vShaderFileName == "" || fShaderFileName == "" {
 		return nil, fmt.Errorf("vertex shader and fragment shader file names should not be empty")
 	}
@@ -73,18 +424,18 @@ func loadShaderFromFile(vShaderFileName, fShaderFileName, gShaderFileName string
 		err                                          error
 	)
 
-	vertexSource, err = loadShaderSourceFromFile(vShaderFileName)
+	vertexSource, err = fs.ReadFile(fsys, vShaderFileName)
 	if err != nil {
 		return nil, fmt.Errorf("failed to load vertex shader source: %w", err)
 	}
 
-	fragmentSource, err = loadShaderSourceFromFile(fShaderFileName)
+	fragmentSource, err = fs.ReadFile(fsys, fShaderFileName)
 	if err != nil {
 		return nil, fmt.Errorf("failed to load fragment shader source: %w", err)
 	}
 
 	if gShaderFileName != "" {
-		geometrySource, err = loadShaderSourceFromFile(gShaderFileName)
+		geometrySource, err = fs.ReadFile(fsys, gShaderFileName)
 		if err != nil {
 			return nil, fmt.Errorf("failed to load geometry shader source: %w", err)
 		}
@@ -99,35 +450,61 @@ func loadShaderFromFile(vShaderFileName, fShaderFileName, gShaderFileName string
 	return s, nil
 }
 
-func loadShaderSourceFromFile(fileName string) ([]byte, error) {
-	file, err := os.Open(fileName)
-	if err != nil {
-		return nil, fmt.Errorf("failed to open file: %w", err)
+func loadTextureFromFile(fsys fs.FS, fileName string, options TextureOptions) (*texture.Texture2D, error) {
+	t := texture.NewTexture2D()
+	if err := uploadImage(fsys, t, fileName, options); err != nil {
+		t.Delete()
+		return nil, err
 	}
-	defer file.Close()
 
-	source, err := io.ReadAll(file)
+	return t, nil
+}
+
+// uploadImage replaces the contents of a texture with an image file. The
+// pixels are always uploaded as RGBA, without alpha the texture drops it.
+func uploadImage(fsys fs.FS, t *texture.Texture2D, fileName string, options TextureOptions) error {
+	pixels, err := decodeImage(fsys, fileName, options)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read source: %w", err)
+		return err
+	}
+
+	t.InternalFormat, t.ImageFormat = gl.RGB, gl.RGBA
+	if options.Alpha {
+		t.InternalFormat = gl.RGBA
 	}
 
-	return source, nil
+	t.FilterMin, t.FilterMax = gl.LINEAR, gl.LINEAR
+	if options.Nearest {
+		t.FilterMin, t.FilterMax = gl.NEAREST, gl.NEAREST
+	}
+
+	t.Generate(int32(pixels.Rect.Dx()), int32(pixels.Rect.Dy()), gl.Ptr(pixels.Pix))
+
+	return nil
 }
 
-func loadTextureFromFile(fileName string, alpha bool) (*texture.Texture2D, error) {
-	t := texture.NewTexture2D()
-	if alpha {
-		t.InternalFormat = gl.RGBA
-		t.ImageFormat = gl.RGBA
+// decodeImage reads an image file as RGBA. Without alpha every pixel is made
+// opaque, as if the alpha channel was dropped.
+func decodeImage(fsys fs.FS, fileName string, options TextureOptions) (*image.NRGBA, error) {
+	file, err := fsys.Open(fileName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open image: %w", err)
 	}
+	defer file.Close()
 
-	data, width, height, _, cleanup, err := stbi.Load(fileName, false, 0)
+	img, _, err := image.Decode(file)
 	if err != nil {
-		return nil, fmt.Errorf("failed to load image: %w", err)
+		return nil, fmt.Errorf("failed to decode image: %w", err)
 	}
-	defer cleanup()
 
-	t.Generate(width, height, data)
+	pixels := image.NewNRGBA(image.Rect(0, 0, img.Bounds().Dx(), img.Bounds().Dy()))
+	draw.Draw(pixels, pixels.Bounds(), img, img.Bounds().Min, draw.Src)
 
-	return t, nil
+	if !options.Alpha {
+		for i := 3; i < len(pixels.Pix); i += 4 {
+			pixels.Pix[i] = 255
+		}
+	}
+
+	return pixels, nil
 }
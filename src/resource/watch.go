@@ -0,0 +1,69 @@
+package resource
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"time"
+)
+
+type fileState struct {
+	modTime time.Time
+	size    int64
+}
+
+// Watcher polls directories for files that were created or changed since
+// the last poll. Polling keeps it portable, the resource directories are
+// small enough to walk a few times a second.
+type Watcher struct {
+	dirs  []string
+	files map[string]fileState
+}
+
+func NewWatcher(dirs ...string) (*Watcher, error) {
+	w := &Watcher{dirs: dirs, files: make(map[string]fileState)}
+	if _, err := w.Poll(); err != nil {
+		return nil, fmt.Errorf("failed to scan resource directories: %w", err)
+	}
+
+	return w, nil
+}
+
+// Poll returns the files that were created or changed since the last call.
+func (w *Watcher) Poll() ([]string, error) {
+	var changed []string
+
+	for _, dir := range w.dirs {
+		err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+
+			if d.IsDir() {
+				return nil
+			}
+
+			info, err := d.Info()
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+
+			state := fileState{modTime: info.ModTime(), size: info.Size()}
+			if old, ok := w.files[path]; !ok || !old.modTime.Equal(state.modTime) || old.size != state.size {
+				w.files[path] = state
+				changed = append(changed, path)
+			}
+
+			return nil
+		})
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("failed to walk %s: %w", dir, err)
+		}
+	}
+
+	return changed, nil
+}
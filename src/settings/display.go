@@ -0,0 +1,94 @@
+package settings
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+type WindowMode int
+
+const (
+	Windowed WindowMode = iota
+	// Borderless covers the monitor with an undecorated window and keeps the
+	// desktop video mode.
+	Borderless
+	// Fullscreen takes the monitor over exclusively and can change its
+	// video mode.
+	Fullscreen
+
+	windowModeCount
+)
+
+var windowModeNames = [windowModeCount]string{
+	Windowed:   "windowed",
+	Borderless: "borderless",
+	Fullscreen: "fullscreen",
+}
+
+func (m WindowMode) String() string {
+	if m < 0 || m >= windowModeCount {
+		return "unknown"
+	}
+
+	return windowModeNames[m]
+}
+
+func ParseWindowMode(name string) (WindowMode, error) {
+	for m, n := range windowModeNames {
+		if n == name {
+			return WindowMode(m), nil
+		}
+	}
+
+	return 0, fmt.Errorf("expected %s", strings.Join(windowModeNames[:], ", "))
+}
+
+// VideoMode is a resolution and refresh rate for exclusive fullscreen. The
+// zero value keeps the desktop video mode of the monitor.
+type VideoMode struct {
+	Width       int
+	Height      int
+	RefreshRate int
+}
+
+const desktopVideoMode = "desktop"
+
+func (m VideoMode) String() string {
+	if m == (VideoMode{}) {
+		return desktopVideoMode
+	}
+
+	return fmt.Sprintf("%dx%d@%d", m.Width, m.Height, m.RefreshRate)
+}
+
+// ParseVideoMode reads "desktop" or WIDTHxHEIGHT@RATE, for example
+// "1920x1080@60".
+func ParseVideoMode(value string) (VideoMode, error) {
+	if value == desktopVideoMode {
+		return VideoMode{}, nil
+	}
+
+	size, rate, ok := strings.Cut(value, "@")
+	w, h, ok2 := strings.Cut(size, "x")
+	if !ok || !ok2 {
+		return VideoMode{}, errors.New("expected desktop or WIDTHxHEIGHT@RATE")
+	}
+
+	var (
+		m   VideoMode
+		err error
+	)
+
+	if m.Width, err = parseInt(w, 320, 7680); err != nil {
+		return VideoMode{}, fmt.Errorf("width: %w", err)
+	}
+	if m.Height, err = parseInt(h, 240, 4320); err != nil {
+		return VideoMode{}, fmt.Errorf("height: %w", err)
+	}
+	if m.RefreshRate, err = parseInt(rate, 1, 1000); err != nil {
+		return VideoMode{}, fmt.Errorf("refresh rate: %w", err)
+	}
+
+	return m, nil
+}
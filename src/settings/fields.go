@@ -0,0 +1,365 @@
+package settings
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type field struct {
+	key    string
+	usage  string
+	isBool bool
+	get    func(s *Settings) string
+	set    func(s *Settings, value string) error
+}
+
+var fields = []field{
+	{
+		key:   "resolution",
+		usage: "window size as WIDTHxHEIGHT, the game is scaled to fit",
+		get:   func(s *Settings) string { return fmt.Sprintf("%dx%d", s.Width, s.Height) },
+		set: func(s *Settings, value string) error {
+			w, h, ok := strings.Cut(strings.ToLower(value), "x")
+			if !ok {
+				return errors.New("expected WIDTHxHEIGHT")
+			}
+
+			width, err := parseInt(w, 320, 7680)
+			if err != nil {
+				return fmt.Errorf("width: %w", err)
+			}
+
+			height, err := parseInt(h, 240, 4320)
+			if err != nil {
+				return fmt.Errorf("height: %w", err)
+			}
+
+			s.Width, s.Height = width, height
+
+			return nil
+		},
+	},
+	{
+		key:   "title",
+		usage: "window title",
+		get:   func(s *Settings) string { return s.Title },
+		set: func(s *Settings, value string) error {
+			if value == "" {
+				return errors.New("must not be empty")
+			}
+
+			s.Title = value
+
+			return nil
+		},
+	},
+	{
+		key:   "window-mode",
+		usage: "windowed, borderless or fullscreen",
+		get:   func(s *Settings) string { return s.WindowMode.String() },
+		set: func(s *Settings, value string) error {
+			mode, err := ParseWindowMode(value)
+			if err != nil {
+				return err
+			}
+
+			s.WindowMode = mode
+
+			return nil
+		},
+	},
+	{
+		key:   "monitor",
+		usage: "monitor for borderless and fullscreen, 0 is the primary one",
+		get:   func(s *Settings) string { return strconv.Itoa(s.Monitor) },
+		set: func(s *Settings, value string) error {
+			monitor, err := parseInt(value, 0, 15)
+			if err != nil {
+				return err
+			}
+
+			s.Monitor = monitor
+
+			return nil
+		},
+	},
+	{
+		key:   "video-mode",
+		usage: "fullscreen video mode as WIDTHxHEIGHT@RATE, or desktop to keep the current one",
+		get:   func(s *Settings) string { return s.VideoMode.String() },
+		set: func(s *Settings, value string) error {
+			mode, err := ParseVideoMode(value)
+			if err != nil {
+				return err
+			}
+
+			s.VideoMode = mode
+
+			return nil
+		},
+	},
+	{
+		key:    "vsync",
+		usage:  "wait for the display refresh between frames",
+		isBool: true,
+		get:    func(s *Settings) string { return strconv.FormatBool(s.VSync) },
+		set:    func(s *Settings, value string) error { return parseBool(value, &s.VSync) },
+	},
+	{
+		key:   "fps-cap",
+		usage: "frame rate limit while vsync is off, 0 for none",
+		get:   func(s *Settings) string { return strconv.Itoa(s.FPSCap) },
+		set: func(s *Settings, value string) error {
+			limit, err := parseInt(value, 0, 1000)
+			if err != nil {
+				return err
+			}
+
+			s.FPSCap = limit
+
+			return nil
+		},
+	},
+	{
+		key:   "msaa",
+		usage: "multisample anti-aliasing samples: 0, 2, 4, 8 or 16",
+		get:   func(s *Settings) string { return strconv.Itoa(s.Samples) },
+		set: func(s *Settings, value string) error {
+			samples, err := parseInt(value, 0, 16)
+			if err != nil {
+				return err
+			}
+
+			if samples&(samples-1) != 0 || samples == 1 {
+				return errors.New("must be 0, 2, 4, 8 or 16")
+			}
+
+			s.Samples = samples
+
+			return nil
+		},
+	},
+	{
+		key:    "integer-scaling",
+		usage:  "scale the game by whole multiples only",
+		isBool: true,
+		get:    func(s *Settings) string { return strconv.FormatBool(s.IntegerScaling) },
+		set:    func(s *Settings, value string) error { return parseBool(value, &s.IntegerScaling) },
+	},
+	{
+		key:   "master-volume",
+		usage: "overall volume from 0 to 1",
+		get:   func(s *Settings) string { return formatFloat(s.MasterVolume) },
+		set:   func(s *Settings, value string) error { return parseVolume(value, &s.MasterVolume) },
+	},
+	{
+		key:   "music-volume",
+		usage: "background music volume from 0 to 1",
+		get:   func(s *Settings) string { return formatFloat(s.MusicVolume) },
+		set:   func(s *Settings, value string) error { return parseVolume(value, &s.MusicVolume) },
+	},
+	{
+		key:   "effects-volume",
+		usage: "sound effects volume from 0 to 1",
+		get:   func(s *Settings) string { return formatFloat(s.EffectsVolume) },
+		set:   func(s *Settings, value string) error { return parseVolume(value, &s.EffectsVolume) },
+	},
+	{
+		key:   "paddle-speed",
+		usage: "paddle speed in pixels per second",
+		get:   func(s *Settings) string { return formatFloat(float64(s.Gameplay.PlayerVelocity)) },
+		set: func(s *Settings, value string) error {
+			return parseFloat32(value, 50, 5000, &s.Gameplay.PlayerVelocity)
+		},
+	},
+	{
+		key:   "ball-radius",
+		usage: "ball radius in pixels",
+		get:   func(s *Settings) string { return formatFloat(float64(s.Gameplay.BallRadius)) },
+		set: func(s *Settings, value string) error {
+			return parseFloat32(value, 2, 50, &s.Gameplay.BallRadius)
+		},
+	},
+	{
+		key:   "ball-velocity",
+		usage: "launch velocity as X,Y in pixels per second, Y pointing up is negative",
+		get: func(s *Settings) string {
+			v := s.Gameplay.BallVelocity
+			return formatFloat(float64(v.X())) + "," + formatFloat(float64(v.Y()))
+		},
+		set: func(s *Settings, value string) error {
+			x, y, ok := strings.Cut(value, ",")
+			if !ok {
+				return errors.New("expected X,Y")
+			}
+
+			var vx, vy float32
+			if err := parseFloat32(x, -2000, 2000, &vx); err != nil {
+				return fmt.Errorf("x: %w", err)
+			}
+			if err := parseFloat32(y, -2000, -50, &vy); err != nil {
+				return fmt.Errorf("y: %w", err)
+			}
+			if vx == 0 {
+				return errors.New("x must not be zero")
+			}
+
+			s.Gameplay.BallVelocity[0], s.Gameplay.BallVelocity[1] = vx, vy
+
+			return nil
+		},
+	},
+	{
+		key:   "lives",
+		usage: "lives at the start of a level",
+		get:   func(s *Settings) string { return strconv.Itoa(int(s.Gameplay.StartingLives)) },
+		set: func(s *Settings, value string) error {
+			lives, err := parseInt(value, 1, 99)
+			if err != nil {
+				return err
+			}
+
+			s.Gameplay.StartingLives = uint32(lives)
+
+			return nil
+		},
+	},
+	{
+		key:   "particles",
+		usage: "most particles alive at once",
+		get:   func(s *Settings) string { return strconv.Itoa(s.Gameplay.Particles) },
+		set: func(s *Settings, value string) error {
+			particles, err := parseInt(value, 0, 100000)
+			if err != nil {
+				return err
+			}
+
+			s.Gameplay.Particles = particles
+
+			return nil
+		},
+	},
+	{
+		key:    "mouse",
+		usage:  "control the paddle with the mouse",
+		isBool: true,
+		get:    func(s *Settings) string { return strconv.FormatBool(s.Mouse.Enabled) },
+		set:    func(s *Settings, value string) error { return parseBool(value, &s.Mouse.Enabled) },
+	},
+	{
+		key:   "mouse-smoothing",
+		usage: "seconds the paddle takes to catch up with the cursor",
+		get:   func(s *Settings) string { return formatFloat(s.Mouse.Smoothing) },
+		set: func(s *Settings, value string) error {
+			smoothing, err := parseFloat(value, 0, 5)
+			if err != nil {
+				return err
+			}
+
+			s.Mouse.Smoothing = smoothing
+
+			return nil
+		},
+	},
+	{
+		key:   "mouse-max-speed",
+		usage: "fastest the paddle follows the cursor, in pixels per second",
+		get:   func(s *Settings) string { return formatFloat(float64(s.Mouse.MaxSpeed)) },
+		set: func(s *Settings, value string) error {
+			return parseFloat32(value, 50, 10000, &s.Mouse.MaxSpeed)
+		},
+	},
+}
+
+// deprecated turns settings that older files may still contain into their
+// replacement. They are read but never written back.
+var deprecated = map[string]func(value string) (key, replacement string, err error){
+	"fullscreen": func(value string) (string, string, error) {
+		var on bool
+		if err := parseBool(value, &on); err != nil {
+			return "", "", err
+		}
+
+		mode := Windowed
+		if on {
+			mode = Fullscreen
+		}
+
+		return "window-mode", mode.String(), nil
+	},
+}
+
+func lookup(key string) (field, bool) {
+	for _, f := range fields {
+		if f.key == key {
+			return f, true
+		}
+	}
+
+	return field{}, false
+}
+
+func parseInt(value string, lo, hi int) (int, error) {
+	n, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil {
+		return 0, errors.New("not a whole number")
+	}
+
+	if n < lo || n > hi {
+		return 0, fmt.Errorf("must be between %d and %d", lo, hi)
+	}
+
+	return n, nil
+}
+
+func parseFloat(value string, lo, hi float64) (float64, error) {
+	f, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil {
+		return 0, errors.New("not a number")
+	}
+
+	if !(f >= lo && f <= hi) {
+		return 0, fmt.Errorf("must be between %s and %s", formatFloat(lo), formatFloat(hi))
+	}
+
+	return f, nil
+}
+
+func parseFloat32(value string, lo, hi float64, dst *float32) error {
+	f, err := parseFloat(value, lo, hi)
+	if err != nil {
+		return err
+	}
+
+	*dst = float32(f)
+
+	return nil
+}
+
+func parseVolume(value string, dst *float64) error {
+	volume, err := parseFloat(value, 0, 1)
+	if err != nil {
+		return err
+	}
+
+	*dst = volume
+
+	return nil
+}
+
+func parseBool(value string, dst *bool) error {
+	b, err := strconv.ParseBool(strings.TrimSpace(value))
+	if err != nil {
+		return errors.New("expected true or false")
+	}
+
+	*dst = b
+
+	return nil
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 32)
+}
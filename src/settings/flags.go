@@ -0,0 +1,62 @@
+package settings
+
+import (
+	"flag"
+	"fmt"
+)
+
+// Overrides collects the settings given on the command line. They are
+// validated while the flags are parsed and applied after the file is
+// loaded, so flags take precedence.
+type Overrides struct {
+	values map[string]string
+}
+
+type flagValue struct {
+	overrides *Overrides
+	field     field
+	value     string
+}
+
+// RegisterFlags adds a flag for every setting except the key bindings.
+func RegisterFlags(fs *flag.FlagSet) *Overrides {
+	o := &Overrides{values: make(map[string]string)}
+	defaults := Default()
+
+	for _, f := range fields {
+		fs.Var(&flagValue{overrides: o, field: f, value: f.get(defaults)}, f.key, f.usage)
+	}
+
+	return o
+}
+
+func (o *Overrides) Apply(s *Settings) error {
+	for _, f := range fields {
+		if value, ok := o.values[f.key]; ok {
+			if err := s.Set(f.key, value); err != nil {
+				return fmt.Errorf("failed to apply -%s: %w", f.key, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (v *flagValue) String() string {
+	return v.value
+}
+
+func (v *flagValue) Set(value string) error {
+	if err := v.field.set(Default(), value); err != nil {
+		return err
+	}
+
+	v.value = value
+	v.overrides.values[v.field.key] = value
+
+	return nil
+}
+
+func (v *flagValue) IsBoolFlag() bool {
+	return v.field.isBool
+}
@@ -0,0 +1,222 @@
+package settings
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-gl/mathgl/mgl32"
+
+	"breakout/src/controls"
+)
+
+const bindingPrefix = "bind."
+
+// Settings is everything the player can change without rebuilding the game.
+// It is read from a key = value file in the user config directory and can be
+// overridden by command line flags of the same name.
+type Settings struct {
+	Width      int
+	Height     int
+	Title      string
+	WindowMode WindowMode
+	Monitor    int
+	VideoMode  VideoMode
+	VSync      bool
+	Samples    int
+
+	// FPSCap limits the frame rate while vsync is off, zero does not.
+	FPSCap int
+
+	// IntegerScaling only scales the game by whole multiples, with wider
+	// bars around it, so every game pixel covers the same screen pixels.
+	IntegerScaling bool
+
+	MasterVolume  float64
+	MusicVolume   float64
+	EffectsVolume float64
+
+	Gameplay Gameplay
+	Mouse    controls.MouseSettings
+	Bindings *controls.Bindings
+}
+
+// Gameplay tunes the simulation. Versus matches ignore it and play with the
+// defaults, both boards have to simulate the same rules.
+type Gameplay struct {
+	PlayerVelocity float32
+	BallRadius     float32
+	BallVelocity   mgl32.Vec2
+	StartingLives  uint32
+	Particles      int
+}
+
+func Default() *Settings {
+	return &Settings{
+		Width:   800,
+		Height:  600,
+		Title:   "Breakout",
+		VSync:   true,
+		Samples: 4,
+
+		MasterVolume:  1,
+		MusicVolume:   1,
+		EffectsVolume: 1,
+
+		Gameplay: DefaultGameplay(),
+		Mouse: controls.MouseSettings{
+			Smoothing: 0.05,
+			MaxSpeed:  1500,
+		},
+		Bindings: controls.DefaultBindings(),
+	}
+}
+
+func DefaultGameplay() Gameplay {
+	return Gameplay{
+		PlayerVelocity: 500,
+		BallRadius:     12.5,
+		BallVelocity:   mgl32.Vec2{100, -350},
+		StartingLives:  3,
+		Particles:      2000,
+	}
+}
+
+// Load reads a settings file on top of the defaults. A missing file is not
+// an error. Every bad line is reported, not only the first one.
+func Load(path string) (*Settings, error) {
+	s := Default()
+
+	file, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open settings: %w", err)
+	}
+	defer file.Close()
+
+	var errs []error
+
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		key, value, ok := strings.Cut(text, "=")
+		if !ok {
+			errs = append(errs, fmt.Errorf("line %d: expected key = value, got %q", line, text))
+			continue
+		}
+
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+
+		if upgrade, ok := deprecated[key]; ok {
+			replacement, replacementValue, err := upgrade(value)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("line %d: invalid value %q for %s: %w", line, value, key, err))
+				continue
+			}
+
+			log.Printf("warning: %s line %d: %s is deprecated, use %s = %s", path, line, key, replacement, replacementValue)
+			key, value = replacement, replacementValue
+		}
+
+		if err := s.Set(key, value); err != nil {
+			errs = append(errs, fmt.Errorf("line %d: %w", line, err))
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read settings: %w", err)
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return nil, fmt.Errorf("invalid settings file %s:\n%w", path, err)
+	}
+
+	return s, nil
+}
+
+// Set validates and applies one setting. Key bindings use the "bind." prefix
+// followed by the binding name, for example "bind.p1.move_left".
+func (s *Settings) Set(key, value string) error {
+	if name, ok := strings.CutPrefix(key, bindingPrefix); ok {
+		if err := s.Bindings.Parse(name, value); err != nil {
+			return fmt.Errorf("invalid binding %s: %w", key, err)
+		}
+
+		return nil
+	}
+
+	f, ok := lookup(key)
+	if !ok {
+		return fmt.Errorf("unknown setting %q", key)
+	}
+
+	if err := f.set(s, value); err != nil {
+		return fmt.Errorf("invalid value %q for %s: %w", value, key, err)
+	}
+
+	return nil
+}
+
+// Get formats one setting the way Set reads it.
+func (s *Settings) Get(key string) (string, error) {
+	if name, ok := strings.CutPrefix(key, bindingPrefix); ok {
+		return s.Bindings.Format(name)
+	}
+
+	f, ok := lookup(key)
+	if !ok {
+		return "", fmt.Errorf("unknown setting %q", key)
+	}
+
+	return f.get(s), nil
+}
+
+func (s *Settings) Save(path string) error {
+	var sb strings.Builder
+	sb.WriteString("# Breakout settings, generated by the settings screen\n")
+
+	for _, f := range fields {
+		fmt.Fprintf(&sb, "\n# %s\n%s = %s\n", f.usage, f.key, f.get(s))
+	}
+
+	sb.WriteString("\n# Key bindings, comma separated key names\n")
+	for _, name := range controls.Names() {
+		keys, err := s.Bindings.Format(name)
+		if err != nil {
+			return fmt.Errorf("failed to format binding: %w", err)
+		}
+
+		fmt.Fprintf(&sb, "%s%s = %s\n", bindingPrefix, name, keys)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create settings directory: %w", err)
+	}
+
+	if err := os.WriteFile(path, []byte(sb.String()), 0o644); err != nil {
+		return fmt.Errorf("failed to write settings: %w", err)
+	}
+
+	return nil
+}
+
+// DefaultPath returns the per-user settings file location.
+func DefaultPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "settings.cfg"
+	}
+
+	return filepath.Join(dir, "breakout", "settings.cfg")
+}
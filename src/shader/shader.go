@@ -2,6 +2,7 @@ package shader
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/go-gl/gl/v3.3-core/gl"
 	"github.com/go-gl/mathgl/mgl32"
@@ -194,14 +195,14 @@ func checkCompileError(ID uint32, t compileType) error {
 		if success == gl.FALSE {
 			gl.GetShaderInfoLog(ID, logLength, nil, gl.Str(logMessage))
 
-			return fmt.Errorf("failed to compile shader: %v", logMessage)
+			return fmt.Errorf("failed to compile shader: %v", strings.TrimRight(logMessage, "\x00"))
 		}
 	case programCompileType:
 		gl.GetProgramiv(ID, gl.LINK_STATUS, &success)
 		if success == gl.FALSE {
 			gl.GetProgramInfoLog(ID, logLength, nil, gl.Str(logMessage))
 
-			return fmt.Errorf("failed to link program: %v", logMessage)
+			return fmt.Errorf("failed to link program: %v", strings.TrimRight(logMessage, "\x00"))
 		}
 	}
 
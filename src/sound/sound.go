@@ -2,9 +2,11 @@ package sound
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"io"
-	"os"
+	"io/fs"
+	"log"
 	"strings"
 
 	"github.com/hajimehoshi/ebiten/v2/audio/wav"
@@ -16,26 +18,21 @@ const (
 	samplingRate  = 44100
 	numOfChannels = 2
 	audioBitDepth = 2
-
-	bgMusicFileName     = "resources/sounds/breakout.mp3"
-	nsbBleepFileName    = "resources/sounds/bleep.mp3"
-	sbBleepFileName     = "resources/sounds/solid.wav"
-	powerUpFileName     = "resources/sounds/powerup.wav"
-	paddleBleepFileName = "resources/sounds/bleep.wav"
 )
 
+// Player plays sound effects and looping music by their logical names.
+// Names whose files are missing stay silent.
 type Player struct {
 	context *oto.Context
+	fsys    fs.FS
 
-	bgMusic     oto.Player
-	bgMusicStop chan struct{}
-	nsbBleep    oto.Player
-	sbBleep     oto.Player
-	powerUp     oto.Player
-	paddleBleep oto.Player
+	music     map[string]oto.Player
+	musicStop chan struct{}
+	effects   map[string]oto.Player
 }
 
-func NewPlayer() (*Player, error) {
+// NewPlayer loads the effects and music, logical names to paths in fsys.
+func NewPlayer(fsys fs.FS, effects, music map[string]string) (*Player, error) {
 	context, readyChan, err := oto.NewContext(samplingRate, numOfChannels, audioBitDepth)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create context: %w", err)
@@ -43,50 +40,44 @@ func NewPlayer() (*Player, error) {
 
 	<-readyChan
 
-	p := &Player{context: context}
-
-	if err = p.initSounds(); err != nil {
-		return nil, fmt.Errorf("failed to init sounds: %w", err)
+	p := &Player{
+		context:   context,
+		fsys:      fsys,
+		musicStop: make(chan struct{}),
 	}
 
-	return p, nil
-}
-
-func (p *Player) initSounds() error {
-	var err error
-
-	p.bgMusic, err = p.initSoundPlayer(bgMusicFileName)
-	if err != nil {
-		return fmt.Errorf("failed to init bg sound player: %w", err)
+	if p.effects, err = p.initSoundPlayers(effects); err != nil {
+		return nil, fmt.Errorf("failed to init sound effects: %w", err)
 	}
 
-	p.bgMusicStop = make(chan struct{})
-
-	p.nsbBleep, err = p.initSoundPlayer(nsbBleepFileName)
-	if err != nil {
-		return fmt.Errorf("failed to init non solid block bleep player: %w", err)
+	if p.music, err = p.initSoundPlayers(music); err != nil {
+		return nil, fmt.Errorf("failed to init music: %w", err)
 	}
 
-	p.sbBleep, err = p.initSoundPlayer(sbBleepFileName)
-	if err != nil {
-		return fmt.Errorf("failed to init solid block bleep player: %w", err)
-	}
+	return p, nil
+}
 
-	p.powerUp, err = p.initSoundPlayer(powerUpFileName)
-	if err != nil {
-		return fmt.Errorf("failed to init power up player: %w", err)
-	}
+func (p *Player) initSoundPlayers(files map[string]string) (map[string]oto.Player, error) {
+	players := make(map[string]oto.Player, len(files))
 
-	p.paddleBleep, err = p.initSoundPlayer(paddleBleepFileName)
-	if err != nil {
-		return fmt.Errorf("failed to init paddle bleep player: %w", err)
+	for name, fileName := range files {
+		player, err := p.initSoundPlayer(fileName)
+		if errors.Is(err, fs.ErrNotExist) {
+			log.Printf("warning: %s stays silent: %v", name, err)
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to init %s player: %w", name, err)
+		}
+
+		players[name] = player
 	}
 
-	return nil
+	return players, nil
 }
 
 func (p *Player) initSoundPlayer(fileName string) (oto.Player, error) {
-	file, err := os.ReadFile(fileName)
+	file, err := fs.ReadFile(p.fsys, fileName)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read sound file '%s': %w", fileName, err)
 	}
@@ -109,28 +100,32 @@ func (p *Player) initSoundPlayer(fileName string) (oto.Player, error) {
 	return p.context.NewPlayer(decoder), nil
 }
 
-func (p *Player) PlayBgMusic() {
-	playLoop(p.bgMusic, p.bgMusicStop)
-}
-
-func (p *Player) PlayNonSolidBlockBleep() {
-	play(p.nsbBleep)
+// PlayMusic loops a music track until the player is cleaned up.
+func (p *Player) PlayMusic(name string) {
+	if music, ok := p.music[name]; ok {
+		playLoop(music, p.musicStop)
+	}
 }
 
-func (p *Player) PlaySolidBlockBleep() {
-	play(p.sbBleep)
+func (p *Player) Play(name string) {
+	if effect, ok := p.effects[name]; ok {
+		play(effect)
+	}
 }
 
-func (p *Player) PlayPowerUp() {
-	play(p.powerUp)
-}
+// SetVolume scales the music and the sound effects, both from 0 to 1.
+func (p *Player) SetVolume(music, effects float64) {
+	for _, player := range p.music {
+		player.SetVolume(music)
+	}
 
-func (p *Player) PlayPaddleBleep() {
-	play(p.paddleBleep)
+	for _, player := range p.effects {
+		player.SetVolume(effects)
+	}
 }
 
 func (p *Player) Cleanup() error {
-	close(p.bgMusicStop)
+	close(p.musicStop)
 
 	if err := p.context.Suspend(); err != nil {
 		return fmt.Errorf("failed to suspend context: %w", err)
@@ -0,0 +1,214 @@
+package spectate
+
+import (
+	"bufio"
+	_ "embed"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"sync"
+)
+
+const clientQueueSize = 256
+
+//go:embed viewer.html
+var viewerPage []byte
+
+type frame struct {
+	opcode  byte
+	payload []byte
+}
+
+type client struct {
+	conn    net.Conn
+	rw      *bufio.ReadWriter
+	send    chan frame
+	waiting bool
+	once    sync.Once
+}
+
+func (c *client) close() {
+	c.once.Do(func() {
+		close(c.send)
+		c.conn.Close()
+	})
+}
+
+// Server streams game messages to websocket spectators. New spectators
+// receive nothing until the next full snapshot is published.
+type Server struct {
+	mu      sync.Mutex
+	clients map[*client]struct{}
+
+	listener net.Listener
+	http     *http.Server
+}
+
+func NewServer(address string) (*Server, error) {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen: %w", err)
+	}
+
+	s := &Server{
+		clients:  make(map[*client]struct{}),
+		listener: listener,
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", s.serveViewer)
+	mux.HandleFunc("/ws", s.serveWebSocket)
+	s.http = &http.Server{Handler: mux}
+
+	go func() {
+		if err := s.http.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Println("spectator server stopped:", err)
+		}
+	}()
+
+	return s, nil
+}
+
+func (s *Server) Address() string {
+	return s.listener.Addr().String()
+}
+
+func (s *Server) serveViewer(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(viewerPage)
+}
+
+func (s *Server) serveWebSocket(w http.ResponseWriter, r *http.Request) {
+	conn, rw, err := upgrade(w, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	c := &client{
+		conn:    conn,
+		rw:      rw,
+		send:    make(chan frame, clientQueueSize),
+		waiting: true,
+	}
+
+	s.mu.Lock()
+	s.clients[c] = struct{}{}
+	s.mu.Unlock()
+
+	go s.writeLoop(c)
+	s.readLoop(c)
+}
+
+func (s *Server) writeLoop(c *client) {
+	for f := range c.send {
+		if err := writeFrame(c.rw.Writer, f.opcode, f.payload); err != nil {
+			s.remove(c)
+			return
+		}
+	}
+}
+
+func (s *Server) readLoop(c *client) {
+	defer s.remove(c)
+
+	for {
+		opcode, payload, err := readFrame(c.rw.Reader)
+		if err != nil {
+			return
+		}
+
+		switch opcode {
+		case opClose:
+			return
+		case opPing:
+			s.mu.Lock()
+			if _, ok := s.clients[c]; ok {
+				s.enqueue(c, frame{opcode: opPong, payload: payload})
+			}
+			s.mu.Unlock()
+		}
+	}
+}
+
+func (s *Server) remove(c *client) {
+	s.mu.Lock()
+	delete(s.clients, c)
+	s.mu.Unlock()
+
+	c.close()
+}
+
+// HasClients reports whether anyone is watching, so the game can skip
+// building messages nobody receives.
+func (s *Server) HasClients() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.clients) > 0
+}
+
+func (s *Server) HasWaiting() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for c := range s.clients {
+		if c.waiting {
+			return true
+		}
+	}
+
+	return false
+}
+
+// PublishFull sends a full snapshot to spectators that are waiting for one,
+// or to everyone when all is true.
+func (s *Server) PublishFull(msg []byte, all bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for c := range s.clients {
+		if c.waiting || all {
+			c.waiting = false
+			s.enqueue(c, frame{opcode: opText, payload: msg})
+		}
+	}
+}
+
+func (s *Server) Broadcast(msg []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for c := range s.clients {
+		if !c.waiting {
+			s.enqueue(c, frame{opcode: opText, payload: msg})
+		}
+	}
+}
+
+func (s *Server) enqueue(c *client, f frame) {
+	select {
+	case c.send <- f:
+	default:
+		delete(s.clients, c)
+		go c.close()
+	}
+}
+
+func (s *Server) Close() error {
+	s.mu.Lock()
+	for c := range s.clients {
+		delete(s.clients, c)
+		c.close()
+	}
+	s.mu.Unlock()
+
+	return s.http.Close()
+}
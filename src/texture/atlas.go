@@ -0,0 +1,158 @@
+package texture
+
+import (
+	"fmt"
+	"image"
+	"image/draw"
+	"sort"
+
+	"github.com/go-gl/gl/v3.3-core/gl"
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// AtlasPadding is how many edge pixels are repeated around every packed
+// image, enough for linear filtering at the sizes the game scales to.
+const AtlasPadding = 2
+
+// Atlas is a set of pages with many images packed into each, so sprites
+// drawn one after another share a texture.
+type Atlas struct {
+	Pages   []*Texture2D
+	Regions map[string]*Region
+}
+
+// AtlasBuilder packs images into pages of at most Size by Size pixels.
+type AtlasBuilder struct {
+	Size    int
+	Nearest bool
+
+	images []packedImage
+}
+
+type packedImage struct {
+	name string
+	img  *image.NRGBA
+	page int
+	rect image.Rectangle
+}
+
+func NewAtlasBuilder(size int, nearest bool) *AtlasBuilder {
+	return &AtlasBuilder{Size: size, Nearest: nearest}
+}
+
+func (b *AtlasBuilder) Add(name string, img image.Image) {
+	b.images = append(b.images, packedImage{name: name, img: NRGBA(img)})
+}
+
+// Build packs the images and uploads the pages. Images go onto shelves,
+// tallest first, which wastes little space on sprites of similar heights.
+func (b *AtlasBuilder) Build() (*Atlas, error) {
+	pages, err := b.pack()
+	if err != nil {
+		return nil, err
+	}
+
+	a := &Atlas{Regions: make(map[string]*Region, len(b.images))}
+
+	for _, page := range pages {
+		t := NewTexture2D()
+		t.InternalFormat, t.ImageFormat = gl.RGBA, gl.RGBA
+		t.WrapS, t.WrapT = gl.CLAMP_TO_EDGE, gl.CLAMP_TO_EDGE
+		if b.Nearest {
+			t.FilterMin, t.FilterMax = gl.NEAREST, gl.NEAREST
+		}
+
+		t.Generate(int32(page.Rect.Dx()), int32(page.Rect.Dy()), gl.Ptr(page.Pix))
+		a.Pages = append(a.Pages, t)
+	}
+
+	for _, p := range b.images {
+		page := a.Pages[p.page]
+		w, h := float32(page.Width), float32(page.Height)
+
+		a.Regions[p.name] = &Region{
+			Texture: page,
+			UV:      mgl32.Vec4{float32(p.rect.Min.X) / w, float32(p.rect.Min.Y) / h, float32(p.rect.Max.X) / w, float32(p.rect.Max.Y) / h},
+			Rect:    p.rect,
+			padding: AtlasPadding,
+		}
+	}
+
+	return a, nil
+}
+
+// Delete frees the pages.
+func (a *Atlas) Delete() {
+	for _, page := range a.Pages {
+		page.Delete()
+	}
+}
+
+func (b *AtlasBuilder) pack() ([]*image.NRGBA, error) {
+	order := make([]int, len(b.images))
+	for i := range order {
+		order[i] = i
+	}
+
+	sort.SliceStable(order, func(i, j int) bool {
+		return b.images[order[i]].img.Rect.Dy() > b.images[order[j]].img.Rect.Dy()
+	})
+
+	var (
+		pages            []*image.NRGBA
+		x, y, shelf, end int
+	)
+
+	for _, i := range order {
+		p := &b.images[i]
+		w, h := p.img.Rect.Dx()+2*AtlasPadding, p.img.Rect.Dy()+2*AtlasPadding
+
+		if w > b.Size || h > b.Size {
+			return nil, fmt.Errorf("%s is %dx%d, larger than the %dx%d atlas page", p.name, p.img.Rect.Dx(), p.img.Rect.Dy(), b.Size, b.Size)
+		}
+
+		if x+w > b.Size {
+			x, y = 0, y+shelf
+			shelf = 0
+		}
+
+		if len(pages) == 0 || y+h > b.Size {
+			pages = append(pages, image.NewNRGBA(image.Rect(0, 0, b.Size, b.Size)))
+			x, y, shelf, end = 0, 0, 0, 0
+		}
+
+		p.page = len(pages) - 1
+		p.rect = image.Rect(x+AtlasPadding, y+AtlasPadding, x+w-AtlasPadding, y+h-AtlasPadding)
+
+		padded := pad(p.img, AtlasPadding)
+		draw.Draw(pages[p.page], image.Rect(x, y, x+w, y+h), padded, image.Point{}, draw.Src)
+
+		x += w
+		if h > shelf {
+			shelf = h
+		}
+
+		if y+shelf > end {
+			end = y + shelf
+		}
+	}
+
+	// The last page only needs to be as tall as what is on it.
+	if len(pages) > 0 {
+		last := pages[len(pages)-1]
+		if height := nextPowerOfTwo(end); height < b.Size {
+			pages[len(pages)-1] = last.SubImage(image.Rect(0, 0, b.Size, height)).(*image.NRGBA)
+		}
+	}
+
+	return pages, nil
+}
+
+func nextPowerOfTwo(n int) int {
+	p := 1
+	for p < n {
+		p *= 2
+	}
+
+	return p
+}
@@ -0,0 +1,87 @@
+package texture
+
+import (
+	"fmt"
+	"image"
+
+	"github.com/go-gl/gl/v3.3-core/gl"
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// DynamicAtlas packs images as they arrive, for caches that only learn what
+// they need while the game runs. Images go onto shelves in arrival order and
+// a full page starts the next one.
+type DynamicAtlas struct {
+	Size  int
+	Pages []*Texture2D
+
+	x, y, shelf int
+}
+
+func NewDynamicAtlas(size int) *DynamicAtlas {
+	return &DynamicAtlas{Size: size}
+}
+
+// Add uploads an image and returns where it went.
+func (a *DynamicAtlas) Add(img image.Image) (*Region, error) {
+	n := NRGBA(img)
+	w, h := n.Rect.Dx()+2*AtlasPadding, n.Rect.Dy()+2*AtlasPadding
+
+	if w > a.Size || h > a.Size {
+		return nil, fmt.Errorf("image is %dx%d, larger than the %dx%d atlas page", n.Rect.Dx(), n.Rect.Dy(), a.Size, a.Size)
+	}
+
+	if a.x+w > a.Size {
+		a.x, a.y = 0, a.y+a.shelf
+		a.shelf = 0
+	}
+
+	if len(a.Pages) == 0 || a.y+h > a.Size {
+		a.addPage()
+	}
+
+	page := a.Pages[len(a.Pages)-1]
+	rect := image.Rect(a.x+AtlasPadding, a.y+AtlasPadding, a.x+w-AtlasPadding, a.y+h-AtlasPadding)
+	size := float32(a.Size)
+
+	region := &Region{
+		Texture: page,
+		UV:      mgl32.Vec4{float32(rect.Min.X) / size, float32(rect.Min.Y) / size, float32(rect.Max.X) / size, float32(rect.Max.Y) / size},
+		Rect:    rect,
+		padding: AtlasPadding,
+	}
+
+	if err := region.Update(n); err != nil {
+		return nil, err
+	}
+
+	a.x += w
+	if h > a.shelf {
+		a.shelf = h
+	}
+
+	return region, nil
+}
+
+// addPage starts a cleared page, nothing is ever sampled from its unused
+// parts but they should not hold garbage either.
+func (a *DynamicAtlas) addPage() {
+	t := NewTexture2D()
+	t.InternalFormat, t.ImageFormat = gl.RGBA, gl.RGBA
+	t.WrapS, t.WrapT = gl.CLAMP_TO_EDGE, gl.CLAMP_TO_EDGE
+
+	blank := make([]uint8, a.Size*a.Size*4)
+	t.Generate(int32(a.Size), int32(a.Size), gl.Ptr(blank))
+
+	a.Pages = append(a.Pages, t)
+	a.x, a.y, a.shelf = 0, 0, 0
+}
+
+// Delete frees the pages.
+func (a *DynamicAtlas) Delete() {
+	for _, page := range a.Pages {
+		page.Delete()
+	}
+
+	a.Pages = nil
+}
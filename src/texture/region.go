@@ -0,0 +1,103 @@
+package texture
+
+import (
+	"fmt"
+	"image"
+	"image/draw"
+
+	"github.com/go-gl/gl/v3.3-core/gl"
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// Region is the part of a texture one sprite or glyph is drawn from, either
+// a whole texture or an image packed into an atlas page. UV holds the
+// minimum and maximum texture coordinates, Rect the same area in pixels.
+type Region struct {
+	Texture *Texture2D
+	UV      mgl32.Vec4
+	Rect    image.Rectangle
+
+	// padding is how far the edge pixels are repeated around the image on
+	// an atlas page, so filtering never samples the neighbours.
+	padding int
+}
+
+// NewRegion covers the whole texture.
+func NewRegion(t *Texture2D) *Region {
+	return &Region{
+		Texture: t,
+		UV:      mgl32.Vec4{0, 0, 1, 1},
+		Rect:    image.Rect(0, 0, int(t.Width), int(t.Height)),
+	}
+}
+
+func (r *Region) Width() int {
+	return r.Rect.Dx()
+}
+
+func (r *Region) Height() int {
+	return r.Rect.Dy()
+}
+
+// Update replaces the pixels of the region with an image of the same size.
+func (r *Region) Update(img *image.NRGBA) error {
+	if img.Rect.Dx() != r.Rect.Dx() || img.Rect.Dy() != r.Rect.Dy() {
+		return fmt.Errorf("image is %dx%d, the region %dx%d", img.Rect.Dx(), img.Rect.Dy(), r.Rect.Dx(), r.Rect.Dy())
+	}
+
+	padded := pad(img, r.padding)
+	area := r.Rect.Inset(-r.padding)
+
+	r.Texture.Bind()
+	gl.PixelStorei(gl.UNPACK_ROW_LENGTH, int32(padded.Stride/4))
+	gl.TexSubImage2D(gl.TEXTURE_2D, 0, int32(area.Min.X), int32(area.Min.Y), int32(area.Dx()), int32(area.Dy()), gl.RGBA, gl.UNSIGNED_BYTE, gl.Ptr(padded.Pix))
+	gl.PixelStorei(gl.UNPACK_ROW_LENGTH, 0)
+
+	return nil
+}
+
+// pad returns the image with its edge pixels repeated padding times around
+// it.
+func pad(img *image.NRGBA, padding int) *image.NRGBA {
+	if padding == 0 {
+		return img
+	}
+
+	w, h := img.Rect.Dx(), img.Rect.Dy()
+	padded := image.NewNRGBA(image.Rect(0, 0, w+2*padding, h+2*padding))
+
+	for y := -padding; y < h+padding; y++ {
+		for x := -padding; x < w+padding; x++ {
+			sx, sy := clamp(x, 0, w-1), clamp(y, 0, h-1)
+			padded.SetNRGBA(x+padding, y+padding, img.NRGBAAt(img.Rect.Min.X+sx, img.Rect.Min.Y+sy))
+		}
+	}
+
+	return padded
+}
+
+func clamp(v, lo, hi int) int {
+	if v < lo {
+		return lo
+	}
+
+	if v > hi {
+		return hi
+	}
+
+	return v
+}
+
+// NRGBA converts an image to non-premultiplied RGBA with its origin at zero,
+// the layout textures are uploaded in.
+func NRGBA(img image.Image) *image.NRGBA {
+	if n, ok := img.(*image.NRGBA); ok && n.Rect.Min == (image.Point{}) {
+		return n
+	}
+
+	bounds := img.Bounds()
+	n := image.NewNRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(n, n.Bounds(), img, bounds.Min, draw.Src)
+
+	return n
+}
@@ -6,6 +6,10 @@ import (
 	"github.com/go-gl/gl/v3.3-core/gl"
 )
 
+// bound is the texture bound to the active unit, binding it again is
+// skipped. Everything that binds 2D textures goes through this package.
+var bound uint32
+
 type Texture2D struct {
 	ID             uint32
 	Width          int32
@@ -37,7 +41,7 @@ func (t *Texture2D) Generate(width, height int32, data unsafe.Pointer) {
 	t.Width = width
 	t.Height = height
 
-	gl.BindTexture(gl.TEXTURE_2D, t.ID)
+	t.Bind()
 	gl.TexImage2D(gl.TEXTURE_2D,
 		0,
 		t.InternalFormat,
@@ -54,9 +58,29 @@ func (t *Texture2D) Generate(width, height int32, data unsafe.Pointer) {
 	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MIN_FILTER, t.FilterMin)
 	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MAG_FILTER, t.FilterMax)
 
-	gl.BindTexture(gl.TEXTURE_2D, 0)
+	Unbind()
 }
 
 func (t *Texture2D) Bind() {
+	if bound == t.ID {
+		return
+	}
+
 	gl.BindTexture(gl.TEXTURE_2D, t.ID)
+	bound = t.ID
+}
+
+func Unbind() {
+	gl.BindTexture(gl.TEXTURE_2D, 0)
+	bound = 0
+}
+
+// Delete frees the texture. Its ID may be handed out again, so it must not
+// stay marked as bound.
+func (t *Texture2D) Delete() {
+	if bound == t.ID {
+		bound = 0
+	}
+
+	gl.DeleteTextures(1, &t.ID)
 }
@@ -0,0 +1,131 @@
+package ui
+
+import "github.com/go-gl/mathgl/mgl32"
+
+type Axis int
+
+const (
+	Vertical Axis = iota
+	Horizontal
+)
+
+// Box stacks its children along an axis with the theme spacing between
+// them. Across the axis every child is stretched to the box.
+type Box struct {
+	Axis  Axis
+	Items []Widget
+}
+
+func VBox(items ...Widget) *Box {
+	return &Box{Axis: Vertical, Items: items}
+}
+
+func HBox(items ...Widget) *Box {
+	return &Box{Axis: Horizontal, Items: items}
+}
+
+func (b *Box) Children() []Widget {
+	return b.Items
+}
+
+func (b *Box) Size(c *Context) mgl32.Vec2 {
+	var (
+		along, across float32
+		main, cross   = b.axes()
+	)
+
+	for i, item := range b.Items {
+		size := item.Size(c)
+		along += size[main]
+		if i > 0 {
+			along += c.Theme.Spacing
+		}
+
+		if size[cross] > across {
+			across = size[cross]
+		}
+	}
+
+	var size mgl32.Vec2
+	size[main], size[cross] = along, across
+
+	return size
+}
+
+// Layout gives every child what it asked for along the axis, the spare room
+// is left after the last one.
+func (b *Box) Layout(c *Context, bounds Rect) {
+	main, cross := b.axes()
+	position := bounds.Min
+
+	for _, item := range b.Items {
+		size := item.Size(c)
+		size[cross] = bounds.Size[cross]
+
+		item.Layout(c, Rect{Min: position, Size: size})
+		position[main] += size[main] + c.Theme.Spacing
+	}
+}
+
+func (b *Box) Draw(c *Context) {
+	for _, item := range b.Items {
+		item.Draw(c)
+	}
+}
+
+func (b *Box) axes() (main, cross int) {
+	if b.Axis == Horizontal {
+		return 0, 1
+	}
+
+	return 1, 0
+}
+
+// Panel draws a background behind its child with the theme padding around
+// it. MinWidth keeps menus from shrinking to their longest line.
+type Panel struct {
+	Child    Widget
+	MinWidth float32
+
+	bounds Rect
+}
+
+func NewPanel(child Widget) *Panel {
+	return &Panel{Child: child}
+}
+
+func (p *Panel) Children() []Widget {
+	return []Widget{p.Child}
+}
+
+func (p *Panel) Size(c *Context) mgl32.Vec2 {
+	size := p.Child.Size(c).Add(mgl32.Vec2{2 * c.Theme.Padding, 2 * c.Theme.Padding})
+	if size.X() < p.MinWidth {
+		size[0] = p.MinWidth
+	}
+
+	return size
+}
+
+func (p *Panel) Layout(c *Context, bounds Rect) {
+	p.bounds = bounds
+	p.Child.Layout(c, bounds.Inset(c.Theme.Padding))
+}
+
+func (p *Panel) Draw(c *Context) {
+	c.Rect(layerPanel, p.bounds, c.Theme.Panel)
+	p.Child.Draw(c)
+}
+
+// Spacer is empty room.
+type Spacer struct {
+	Height float32
+}
+
+func (s *Spacer) Size(*Context) mgl32.Vec2 {
+	return mgl32.Vec2{0, s.Height}
+}
+
+func (s *Spacer) Layout(*Context, Rect) {}
+
+func (s *Spacer) Draw(*Context) {}
@@ -0,0 +1,141 @@
+package ui
+
+import "github.com/go-gl/mathgl/mgl32"
+
+// Screen is a widget tree centered on the canvas. Up and down move the focus
+// between the focusable widgets in tree order, the cursor focuses what it
+// moves over and clicks it.
+type Screen struct {
+	Root   Widget
+	OnBack func()
+
+	// Theme replaces the theme of the context on this screen when set.
+	Theme *Theme
+
+	focus     Focusable
+	cursor    mgl32.Vec2
+	hadCursor bool
+}
+
+func NewScreen(root Widget) *Screen {
+	return &Screen{Root: root}
+}
+
+// Focus moves the focus to w, which should be on the screen.
+func (s *Screen) Focus(w Focusable) {
+	s.focus = w
+}
+
+// Update lays the screen out in bounds and applies the input.
+func (s *Screen) Update(c *Context, bounds Rect, in Input) {
+	defer s.useTheme(c)()
+	s.layout(c, bounds)
+
+	focusables := s.focusables()
+	if len(focusables) == 0 {
+		s.focus = nil
+	} else if index(focusables, s.focus) < 0 {
+		s.focus = focusables[0]
+	}
+
+	in.Moved = in.HasCursor && (!s.hadCursor || in.Cursor != s.cursor)
+	s.cursor, s.hadCursor = in.Cursor, in.HasCursor
+
+	var hovered Focusable
+	if in.HasCursor {
+		for _, f := range focusables {
+			if f.Bounds().Contains(in.Cursor) {
+				hovered = f
+			}
+		}
+	}
+
+	if hovered != nil && (in.Moved || in.Click) {
+		s.focus = hovered
+	}
+
+	if in.Click {
+		if hovered != nil {
+			hovered.Handle(&in)
+		}
+
+		return
+	}
+
+	if s.focus != nil && s.focus.Handle(&in) {
+		return
+	}
+
+	switch {
+	case in.Up && len(focusables) > 0:
+		s.focus = focusables[(index(focusables, s.focus)+len(focusables)-1)%len(focusables)]
+	case in.Down && len(focusables) > 0:
+		s.focus = focusables[(index(focusables, s.focus)+1)%len(focusables)]
+	case in.Back && s.OnBack != nil:
+		s.OnBack()
+	}
+}
+
+func (s *Screen) Draw(c *Context, bounds Rect) {
+	defer s.useTheme(c)()
+	s.layout(c, bounds)
+
+	c.begin(s.focus)
+	s.Root.Draw(c)
+	c.end()
+}
+
+// useTheme switches the context to the screen theme and returns what
+// switches it back.
+func (s *Screen) useTheme(c *Context) func() {
+	if s.Theme == nil {
+		return func() {}
+	}
+
+	previous := c.Theme
+	c.Theme = s.Theme
+
+	return func() { c.Theme = previous }
+}
+
+func (s *Screen) layout(c *Context, bounds Rect) {
+	size := s.Root.Size(c)
+	for i := range size {
+		if size[i] > bounds.Size[i] {
+			size[i] = bounds.Size[i]
+		}
+	}
+
+	min := bounds.Min.Add(bounds.Size.Sub(size).Mul(0.5))
+	s.Root.Layout(c, Rect{Min: min, Size: size})
+}
+
+func (s *Screen) focusables() []Focusable {
+	var list []Focusable
+
+	var walk func(w Widget)
+	walk = func(w Widget) {
+		if f, ok := w.(Focusable); ok && f.CanFocus() {
+			list = append(list, f)
+		}
+
+		if container, ok := w.(Container); ok {
+			for _, child := range container.Children() {
+				walk(child)
+			}
+		}
+	}
+	walk(s.Root)
+
+	return list
+}
+
+func index(list []Focusable, f Focusable) int {
+	for i := range list {
+		if list[i] == f {
+			return i
+		}
+	}
+
+	return -1
+}
@@ -0,0 +1,70 @@
+package ui
+
+import (
+	"github.com/go-gl/mathgl/mgl32"
+
+	"breakout/src/render"
+)
+
+// Theme holds the colors and spacing every widget is drawn with.
+type Theme struct {
+	TextScale float32
+
+	Text     mgl32.Vec3
+	Focus    mgl32.Vec3
+	Disabled mgl32.Vec3
+
+	Panel     mgl32.Vec3
+	Highlight mgl32.Vec3
+	Track     mgl32.Vec3
+	Fill      mgl32.Vec3
+
+	// Padding is the room inside panels and around widget text, Spacing
+	// the gap between the children of a box.
+	Padding float32
+	Spacing float32
+
+	// SliderWidth is the length of a slider track.
+	SliderWidth float32
+
+	Title render.TextStyle
+}
+
+func DefaultTheme() *Theme {
+	return &Theme{
+		TextScale: 0.75,
+
+		Text:     mgl32.Vec3{1, 1, 1},
+		Focus:    mgl32.Vec3{1, 1, 0},
+		Disabled: mgl32.Vec3{0.45, 0.45, 0.45},
+
+		Panel:     mgl32.Vec3{0.08, 0.08, 0.14},
+		Highlight: mgl32.Vec3{0.25, 0.25, 0.45},
+		Track:     mgl32.Vec3{0.25, 0.25, 0.3},
+		Fill:      mgl32.Vec3{0.9, 0.75, 0.2},
+
+		Padding:     8,
+		Spacing:     4,
+		SliderWidth: 160,
+
+		Title: render.TextStyle{
+			Color:        mgl32.Vec3{1, 0.9, 0.5},
+			Outline:      1,
+			OutlineColor: mgl32.Vec3{0.5, 0.1, 0},
+			Glow:         3,
+			GlowColor:    mgl32.Vec4{1, 0.5, 0.1, 0.6},
+		},
+	}
+}
+
+// textColor picks the color of widget text for its state.
+func (t *Theme) textColor(focused, disabled bool) mgl32.Vec3 {
+	switch {
+	case disabled:
+		return t.Disabled
+	case focused:
+		return t.Focus
+	}
+
+	return t.Text
+}
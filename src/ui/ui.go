@@ -0,0 +1,148 @@
+// Package ui is a small retained-mode toolkit for menus and the HUD. Screens
+// hold a tree of widgets that is laid out, navigated and drawn every frame
+// on top of the sprite and text renderers.
+package ui
+
+import (
+	"github.com/go-gl/mathgl/mgl32"
+
+	"breakout/src/render"
+	"breakout/src/texture"
+)
+
+// Rect is an area of the canvas, with the origin at the top left.
+type Rect struct {
+	Min  mgl32.Vec2
+	Size mgl32.Vec2
+}
+
+func (r Rect) Max() mgl32.Vec2 {
+	return r.Min.Add(r.Size)
+}
+
+func (r Rect) Contains(p mgl32.Vec2) bool {
+	max := r.Max()
+
+	return p.X() >= r.Min.X() && p.Y() >= r.Min.Y() && p.X() < max.X() && p.Y() < max.Y()
+}
+
+// Inset shrinks the rect by d on every side.
+func (r Rect) Inset(d float32) Rect {
+	return Rect{Min: r.Min.Add(mgl32.Vec2{d, d}), Size: r.Size.Sub(mgl32.Vec2{2 * d, 2 * d})}
+}
+
+// Input is what the player did this frame, in menu terms. Keyboard and
+// gamepad map onto the directions, Activate and Back.
+type Input struct {
+	Up, Down, Left, Right bool
+	Activate, Back        bool
+
+	// Cursor is in canvas coordinates when HasCursor is set. Moved is
+	// filled in by the screen.
+	Cursor    mgl32.Vec2
+	HasCursor bool
+	Moved     bool
+	Click     bool
+}
+
+// Widget is anything on a screen.
+type Widget interface {
+	// Size is how much room the widget wants.
+	Size(c *Context) mgl32.Vec2
+
+	// Layout places the widget and its children, it may get more room than
+	// it asked for.
+	Layout(c *Context, bounds Rect)
+
+	Draw(c *Context)
+}
+
+// Focusable widgets take input. Only one on a screen has the focus.
+type Focusable interface {
+	Widget
+
+	CanFocus() bool
+	Bounds() Rect
+
+	// Handle reacts to the input while the widget has the focus or was
+	// clicked and reports whether it used it.
+	Handle(in *Input) bool
+}
+
+// Container widgets hold other widgets, the screen looks through them for
+// the focusable ones.
+type Container interface {
+	Children() []Widget
+}
+
+// Context is what widgets draw with. Rectangles go into one sprite batch and
+// text is drawn over it afterwards.
+type Context struct {
+	Theme   *Theme
+	Text    *render.TextRenderer
+	Sprites *render.SpriteRenderer
+
+	// Blank is tinted for panels, highlights and bars.
+	Blank *texture.Region
+
+	focus Focusable
+	texts []queuedText
+}
+
+type queuedText struct {
+	text     string
+	position mgl32.Vec2
+	scale    float32
+	style    render.TextStyle
+	align    render.Align
+}
+
+func NewContext(theme *Theme, text *render.TextRenderer, sprites *render.SpriteRenderer, blank *texture.Region) *Context {
+	return &Context{Theme: theme, Text: text, Sprites: sprites, Blank: blank}
+}
+
+// Focused reports whether w has the focus of the screen being drawn.
+func (c *Context) Focused(w Focusable) bool {
+	return c.focus != nil && c.focus == w
+}
+
+// Rect draws a filled rectangle. Higher layers go on top.
+func (c *Context) Rect(layer int, r Rect, color mgl32.Vec3) {
+	c.Sprites.Draw(layer, c.Blank, r.Min, r.Size, 0, color)
+}
+
+// Label draws text with its top at y, aligned on x.
+func (c *Context) Label(text string, x, y, scale float32, style render.TextStyle, align render.Align) {
+	c.texts = append(c.texts, queuedText{text, mgl32.Vec2{x, y}, scale, style, align})
+}
+
+// scale is the theme text scale unless a widget has its own.
+func (c *Context) scale(s float32) float32 {
+	if s == 0 {
+		return c.Theme.TextScale
+	}
+
+	return s
+}
+
+// Measure is the size of text drawn at scale.
+func (c *Context) Measure(text string, scale float32) mgl32.Vec2 {
+	return c.Text.Measure(text, scale)
+}
+
+func (c *Context) begin(focus Focusable) {
+	c.focus = focus
+	c.texts = c.texts[:0]
+	c.Sprites.Begin()
+}
+
+func (c *Context) end() {
+	c.Sprites.End()
+
+	for i := range c.texts {
+		t := &c.texts[i]
+		c.Text.RenderTextStyled(t.text, t.position.X(), t.position.Y(), t.scale, &t.style, t.align)
+	}
+
+	c.focus = nil
+}
@@ -0,0 +1,442 @@
+package ui
+
+import (
+	"math"
+
+	"github.com/go-gl/mathgl/mgl32"
+
+	"breakout/src/render"
+)
+
+// Layers of the sprite batch, so highlights and bars cover the panels
+// whatever order the widgets draw in.
+const (
+	layerPanel = iota
+	layerHighlight
+	layerTrack
+	layerFill
+)
+
+// Label is text that takes no input. Title labels use the title style of
+// the theme.
+type Label struct {
+	Text  string
+	Scale float32
+	Align render.Align
+	Title bool
+
+	// Style replaces the theme text color when set.
+	Style *render.TextStyle
+
+	bounds Rect
+}
+
+func NewLabel(text string) *Label {
+	return &Label{Text: text}
+}
+
+func NewTitle(text string, scale float32) *Label {
+	return &Label{Text: text, Scale: scale, Align: render.AlignCenter, Title: true}
+}
+
+func (l *Label) Size(c *Context) mgl32.Vec2 {
+	return c.Measure(l.Text, c.scale(l.Scale))
+}
+
+func (l *Label) Layout(_ *Context, bounds Rect) {
+	l.bounds = bounds
+}
+
+func (l *Label) Draw(c *Context) {
+	style := render.TextStyle{Color: c.Theme.Text}
+	switch {
+	case l.Style != nil:
+		style = *l.Style
+	case l.Title:
+		style = c.Theme.Title
+	}
+
+	x := l.bounds.Min.X()
+	switch l.Align {
+	case render.AlignCenter:
+		x += l.bounds.Size.X() / 2
+	case render.AlignRight:
+		x += l.bounds.Size.X()
+	}
+
+	c.Label(l.Text, x, l.bounds.Min.Y(), c.scale(l.Scale), style, l.Align)
+}
+
+// control is the part every focusable widget shares.
+type control struct {
+	Disabled bool
+
+	bounds Rect
+}
+
+func (w *control) CanFocus() bool {
+	return !w.Disabled
+}
+
+func (w *control) Bounds() Rect {
+	return w.bounds
+}
+
+func (w *control) Layout(_ *Context, bounds Rect) {
+	w.bounds = bounds
+}
+
+// row draws the highlight of a focused widget and its label on the left,
+// returning the text style for the rest of the row.
+func (w *control) row(c *Context, self Focusable, label string) render.TextStyle {
+	focused := c.Focused(self)
+	if focused {
+		c.Rect(layerHighlight, w.bounds, c.Theme.Highlight)
+	}
+
+	style := render.TextStyle{Color: c.Theme.textColor(focused, w.Disabled)}
+	c.Label(label, w.bounds.Min.X()+c.Theme.Padding, w.textY(c), c.Theme.TextScale, style, render.AlignLeft)
+
+	return style
+}
+
+// textY centers a line of text in the row.
+func (w *control) textY(c *Context) float32 {
+	return w.bounds.Min.Y() + (w.bounds.Size.Y()-c.Text.LineHeight(c.Theme.TextScale))/2
+}
+
+// rowSize fits a label and a value with padding around and between them.
+func rowSize(c *Context, label string, value float32) mgl32.Vec2 {
+	size := c.Measure(label, c.Theme.TextScale)
+	size[0] += value + 4*c.Theme.Padding
+	size[1] = c.Text.LineHeight(c.Theme.TextScale) + c.Theme.Padding
+
+	return size
+}
+
+// Button calls OnClick when activated.
+type Button struct {
+	control
+
+	Text    string
+	OnClick func()
+}
+
+func NewButton(text string, onClick func()) *Button {
+	return &Button{Text: text, OnClick: onClick}
+}
+
+func (b *Button) Size(c *Context) mgl32.Vec2 {
+	return rowSize(c, b.Text, 0)
+}
+
+func (b *Button) Draw(c *Context) {
+	focused := c.Focused(b)
+	if focused {
+		c.Rect(layerHighlight, b.bounds, c.Theme.Highlight)
+	}
+
+	style := render.TextStyle{Color: c.Theme.textColor(focused, b.Disabled)}
+	c.Label(b.Text, b.bounds.Min.X()+b.bounds.Size.X()/2, b.textY(c), c.Theme.TextScale, style, render.AlignCenter)
+}
+
+func (b *Button) Handle(in *Input) bool {
+	if !in.Activate && !in.Click {
+		return false
+	}
+
+	if b.OnClick != nil {
+		b.OnClick()
+	}
+
+	return true
+}
+
+// Toggle switches a setting on and off.
+type Toggle struct {
+	control
+
+	Text  string
+	Value func() bool
+	Set   func(bool)
+}
+
+func NewToggle(text string, value func() bool, set func(bool)) *Toggle {
+	return &Toggle{Text: text, Value: value, Set: set}
+}
+
+func (t *Toggle) Size(c *Context) mgl32.Vec2 {
+	return rowSize(c, t.Text, c.Measure("off", c.Theme.TextScale).X())
+}
+
+func (t *Toggle) Draw(c *Context) {
+	style := t.row(c, t, t.Text)
+
+	value := "off"
+	if t.Value() {
+		value = "on"
+	}
+
+	c.Label(value, t.bounds.Max().X()-c.Theme.Padding, t.textY(c), c.Theme.TextScale, style, render.AlignRight)
+}
+
+func (t *Toggle) Handle(in *Input) bool {
+	if !in.Activate && !in.Click && !in.Left && !in.Right {
+		return false
+	}
+
+	t.Set(!t.Value())
+
+	return true
+}
+
+// Choice cycles through a list of options. The options are read every frame,
+// so they may depend on other choices.
+type Choice struct {
+	control
+
+	Text    string
+	Options func() []string
+	Value   func() string
+	Set     func(string)
+
+	// Format turns an option into what is shown, when set.
+	Format func(string) string
+}
+
+func NewChoice(text string, options func() []string, value func() string, set func(string)) *Choice {
+	return &Choice{Text: text, Options: options, Value: value, Set: set}
+}
+
+func (ch *Choice) Size(c *Context) mgl32.Vec2 {
+	var widest float32
+	for _, option := range ch.Options() {
+		if w := c.Measure(ch.display(option), c.Theme.TextScale).X(); w > widest {
+			widest = w
+		}
+	}
+
+	return rowSize(c, ch.Text, widest)
+}
+
+func (ch *Choice) Draw(c *Context) {
+	style := ch.row(c, ch, ch.Text)
+	c.Label(ch.display(ch.Value()), ch.bounds.Max().X()-c.Theme.Padding, ch.textY(c), c.Theme.TextScale, style, render.AlignRight)
+}
+
+func (ch *Choice) display(option string) string {
+	if ch.Format != nil {
+		option = ch.Format(option)
+	}
+
+	return "< " + option + " >"
+}
+
+func (ch *Choice) Handle(in *Input) bool {
+	switch {
+	case in.Left:
+		ch.Step(-1)
+	case in.Right, in.Activate, in.Click:
+		ch.Step(1)
+	default:
+		return false
+	}
+
+	return true
+}
+
+// Step moves to the next or previous option. A value that is not one of the
+// options moves to the first or last.
+func (ch *Choice) Step(step int) {
+	options := ch.Options()
+	if len(options) == 0 {
+		return
+	}
+
+	current := ch.Value()
+	next := 0
+	if step < 0 {
+		next = len(options) - 1
+	}
+
+	for i, option := range options {
+		if option == current {
+			next = (i + step + len(options)) % len(options)
+			break
+		}
+	}
+
+	ch.Set(options[next])
+}
+
+// Slider picks a number between Min and Max in steps of Step.
+type Slider struct {
+	control
+
+	Text           string
+	Min, Max, Step float32
+	Value          func() float32
+	Set            func(float32)
+
+	track Rect
+}
+
+func NewSlider(text string, min, max, step float32, value func() float32, set func(float32)) *Slider {
+	return &Slider{Text: text, Min: min, Max: max, Step: step, Value: value, Set: set}
+}
+
+func (s *Slider) Size(c *Context) mgl32.Vec2 {
+	return rowSize(c, s.Text, c.Theme.SliderWidth)
+}
+
+func (s *Slider) Layout(c *Context, bounds Rect) {
+	s.bounds = bounds
+
+	var height float32 = 6
+	s.track = Rect{
+		Min:  mgl32.Vec2{bounds.Max().X() - c.Theme.Padding - c.Theme.SliderWidth, bounds.Min.Y() + (bounds.Size.Y()-height)/2},
+		Size: mgl32.Vec2{c.Theme.SliderWidth, height},
+	}
+}
+
+func (s *Slider) Draw(c *Context) {
+	s.row(c, s, s.Text)
+
+	fill := s.track
+	fill.Size[0] *= (s.Value() - s.Min) / (s.Max - s.Min)
+
+	color := c.Theme.Fill
+	if s.Disabled {
+		color = c.Theme.Disabled
+	}
+
+	c.Rect(layerTrack, s.track, c.Theme.Track)
+	c.Rect(layerFill, fill, color)
+}
+
+func (s *Slider) Handle(in *Input) bool {
+	switch {
+	case in.Left:
+		s.set(s.Value() - s.Step)
+	case in.Right:
+		s.set(s.Value() + s.Step)
+	case in.Click:
+		if in.Cursor.X() < s.track.Min.X() {
+			return true
+		}
+
+		t := (in.Cursor.X() - s.track.Min.X()) / s.track.Size.X()
+		s.set(s.Min + t*(s.Max-s.Min))
+	default:
+		return false
+	}
+
+	return true
+}
+
+// set rounds the value to a step inside the range.
+func (s *Slider) set(value float32) {
+	steps := math.Round(float64((value - s.Min) / s.Step))
+	value = s.Min + float32(steps)*s.Step
+	s.Set(float32(math.Max(float64(s.Min), math.Min(float64(s.Max), float64(value)))))
+}
+
+// List shows Rows of its items at a time and scrolls to keep the selected
+// one in view. Up and down move the selection until it reaches an end,
+// then the focus moves on.
+type List struct {
+	control
+
+	Items      []string
+	Selected   int
+	Rows       int
+	OnChange   func(int)
+	OnActivate func(int)
+
+	top int
+}
+
+func NewList(items []string, selected, rows int) *List {
+	return &List{Items: items, Selected: selected, Rows: rows}
+}
+
+func (l *List) Size(c *Context) mgl32.Vec2 {
+	var width float32
+	for _, item := range l.Items {
+		if w := c.Measure(item, c.Theme.TextScale).X(); w > width {
+			width = w
+		}
+	}
+
+	return mgl32.Vec2{width + 2*c.Theme.Padding, float32(l.Rows) * l.rowHeight(c)}
+}
+
+func (l *List) rowHeight(c *Context) float32 {
+	return c.Text.LineHeight(c.Theme.TextScale) + c.Theme.Spacing
+}
+
+func (l *List) Draw(c *Context) {
+	focused := c.Focused(l)
+	height := l.rowHeight(c)
+
+	if l.Selected < l.top {
+		l.top = l.Selected
+	}
+	if l.Selected >= l.top+l.Rows {
+		l.top = l.Selected - l.Rows + 1
+	}
+
+	for i := l.top; i < len(l.Items) && i < l.top+l.Rows; i++ {
+		row := Rect{
+			Min:  l.bounds.Min.Add(mgl32.Vec2{0, float32(i-l.top) * height}),
+			Size: mgl32.Vec2{l.bounds.Size.X(), height},
+		}
+
+		style := render.TextStyle{Color: c.Theme.textColor(false, l.Disabled)}
+		if i == l.Selected {
+			color := c.Theme.Track
+			if focused {
+				color = c.Theme.Highlight
+				style.Color = c.Theme.Focus
+			}
+
+			c.Rect(layerHighlight, row, color)
+		}
+
+		c.Label(l.Items[i], row.Min.X()+c.Theme.Padding, row.Min.Y()+c.Theme.Spacing/2, c.Theme.TextScale, style, render.AlignLeft)
+	}
+}
+
+func (l *List) Handle(in *Input) bool {
+	switch {
+	case in.Up && l.Selected > 0:
+		l.selectItem(l.Selected - 1)
+	case in.Down && l.Selected < len(l.Items)-1:
+		l.selectItem(l.Selected + 1)
+	case in.Click:
+		row := l.top + int((in.Cursor.Y()-l.bounds.Min.Y())/(l.bounds.Size.Y()/float32(l.Rows)))
+		if row >= 0 && row < len(l.Items) {
+			l.selectItem(row)
+			l.activate()
+		}
+	case in.Activate:
+		l.activate()
+	default:
+		return false
+	}
+
+	return true
+}
+
+func (l *List) selectItem(i int) {
+	l.Selected = i
+	if l.OnChange != nil {
+		l.OnChange(i)
+	}
+}
+
+func (l *List) activate() {
+	if l.OnActivate != nil {
+		l.OnActivate(l.Selected)
+	}
+}